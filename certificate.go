@@ -10,8 +10,10 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/pion/dtls"
@@ -58,6 +60,107 @@ func NewCertificate(key crypto.PrivateKey, tpl x509.Certificate) (*Certificate,
 	return &Certificate{privateKey: key, x509Cert: cert}, nil
 }
 
+// CertificateFromX509 creates a Certificate from an existing private key and
+// x509 certificate. This allows a long-lived identity to be reused across
+// PeerConnections instead of generating a new certificate for each of them.
+func CertificateFromX509(privateKey crypto.PrivateKey, certificate *x509.Certificate) (*Certificate, error) {
+	if err := checkCertificateKey(privateKey, certificate); err != nil {
+		return nil, err
+	}
+	return &Certificate{privateKey: privateKey, x509Cert: certificate}, nil
+}
+
+// CertificateFromPEM creates a Certificate from a string containing the PEM
+// blocks of an x509 certificate and its private key, as returned by PEM.
+func CertificateFromPEM(pems string) (*Certificate, error) {
+	var cert *x509.Certificate
+	var privateKey crypto.PrivateKey
+
+	rest := []byte(pems)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		var err error
+		switch block.Type {
+		case "CERTIFICATE":
+			if cert == nil {
+				cert, err = x509.ParseCertificate(block.Bytes)
+			}
+		case "PRIVATE KEY":
+			privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, &rtcerr.SyntaxError{Err: err}
+		}
+	}
+
+	if cert == nil || privateKey == nil {
+		return nil, &rtcerr.SyntaxError{Err: ErrCertificatePEM}
+	}
+
+	return CertificateFromX509(privateKey, cert)
+}
+
+// PEM returns the certificate encoded as two PEM blocks: one for the x509
+// certificate and the other for the PKCS #8 private key.
+func (c Certificate) PEM() (string, error) {
+	var o strings.Builder
+	if err := pem.Encode(&o, &pem.Block{Type: "CERTIFICATE", Bytes: c.x509Cert.Raw}); err != nil {
+		return "", err
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(c.privateKey)
+	if err != nil {
+		return "", err
+	}
+	if err := pem.Encode(&o, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
+		return "", err
+	}
+
+	return o.String(), nil
+}
+
+// X509Certificate returns the x509 certificate used by this Certificate.
+func (c Certificate) X509Certificate() *x509.Certificate {
+	return c.x509Cert
+}
+
+// PrivateKey returns the private key used by this Certificate.
+func (c Certificate) PrivateKey() crypto.PrivateKey {
+	return c.privateKey
+}
+
+// checkCertificateKey ensures privateKey is of a supported type and is the
+// counterpart of the public key embedded in cert.
+func checkCertificateKey(privateKey crypto.PrivateKey, cert *x509.Certificate) error {
+	if cert == nil {
+		return &rtcerr.InvalidAccessError{Err: ErrCertificateKeyMismatch}
+	}
+
+	switch sk := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if pk, ok := cert.PublicKey.(*rsa.PublicKey); !ok || pk.N.Cmp(sk.N) != 0 || pk.E != sk.E {
+			return &rtcerr.InvalidAccessError{Err: ErrCertificateKeyMismatch}
+		}
+	case *ecdsa.PrivateKey:
+		if pk, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok || pk.X.Cmp(sk.X) != 0 || pk.Y.Cmp(sk.Y) != 0 {
+			return &rtcerr.InvalidAccessError{Err: ErrCertificateKeyMismatch}
+		}
+	default:
+		return &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
+	}
+
+	return nil
+}
+
 // Equals determines if two certificates are identical by comparing both the
 // secretKeys and x509Certificates.
 func (c Certificate) Equals(o Certificate) bool {
@@ -91,34 +194,46 @@ func (c Certificate) Expires() time.Time {
 	return c.x509Cert.NotAfter
 }
 
-// GetFingerprints returns the list of certificate fingerprints, one of which
-// is computed with the digest algorithm used in the certificate signature.
+// GetFingerprints returns the list of certificate fingerprints, one for each
+// of the SHA-256, SHA-384 and SHA-512 digest algorithms.
 func (c Certificate) GetFingerprints() ([]DTLSFingerprint, error) {
-	fingerprintAlgorithms := []dtls.HashAlgorithm{dtls.HashAlgorithmSHA256}
-	res := make([]DTLSFingerprint, len(fingerprintAlgorithms))
+	fingerprintAlgorithms := []dtls.HashAlgorithm{
+		dtls.HashAlgorithmSHA256,
+		dtls.HashAlgorithmSHA384,
+		dtls.HashAlgorithmSHA512,
+	}
+	res := make([]DTLSFingerprint, 0, len(fingerprintAlgorithms))
 
-	i := 0
 	for _, algo := range fingerprintAlgorithms {
 		value, err := dtls.Fingerprint(c.x509Cert, algo)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create fingerprint: %v", err)
 		}
-		res[i] = DTLSFingerprint{
+		res = append(res, DTLSFingerprint{
 			Algorithm: algo.String(),
 			Value:     value,
-		}
+		})
 	}
 
-	return res[:i+1], nil
+	return res, nil
 }
 
 // GenerateCertificate causes the creation of an X.509 certificate and
 // corresponding private key.
 func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
-	origin := make([]byte, 16)
-	/* #nosec */
-	if _, err := rand.Read(origin); err != nil {
-		return nil, &rtcerr.UnknownError{Err: err}
+	return generateCertificate(secretKey, defaultCertificateValidity, pkix.Name{})
+}
+
+// generateCertificate creates a self-signed certificate valid from now on for
+// validity. An empty subject is replaced by a random common name.
+func generateCertificate(secretKey crypto.PrivateKey, validity time.Duration, subject pkix.Name) (*Certificate, error) {
+	if subject.CommonName == "" && len(subject.Names) == 0 && len(subject.Organization) == 0 {
+		origin := make([]byte, 16)
+		/* #nosec */
+		if _, err := rand.Read(origin); err != nil {
+			return nil, &rtcerr.UnknownError{Err: err}
+		}
+		subject.CommonName = hex.EncodeToString(origin)
 	}
 
 	// Max random value, a 130-bits integer, i.e 2^130 - 1
@@ -131,18 +246,19 @@ func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
 		return nil, &rtcerr.UnknownError{Err: err}
 	}
 
+	now := time.Now()
 	return NewCertificate(secretKey, x509.Certificate{
 		ExtKeyUsage: []x509.ExtKeyUsage{
 			x509.ExtKeyUsageClientAuth,
 			x509.ExtKeyUsageServerAuth,
 		},
 		BasicConstraintsValid: true,
-		NotBefore:             time.Now(),
+		NotBefore:             now,
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		NotAfter:              time.Now().AddDate(0, 1, 0),
+		NotAfter:              now.Add(validity),
 		SerialNumber:          serialNumber,
 		Version:               2,
-		Subject:               pkix.Name{CommonName: hex.EncodeToString(origin)},
+		Subject:               subject,
 		IsCA:                  true,
 	})
 }
@@ -3,6 +3,7 @@
 package webrtc
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -88,3 +89,84 @@ func TestGenerateCertificateExpires(t *testing.T) {
 	now := time.Now()
 	assert.False(t, cert.Expires().IsZero() || now.After(cert.Expires()))
 }
+
+func TestCertificatePEM(t *testing.T) {
+	for _, newKey := range []func() (crypto.PrivateKey, error){
+		func() (crypto.PrivateKey, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) },
+		func() (crypto.PrivateKey, error) { return rsa.GenerateKey(rand.Reader, 2048) },
+	} {
+		sk, err := newKey()
+		assert.Nil(t, err)
+
+		cert, err := GenerateCertificate(sk)
+		assert.Nil(t, err)
+
+		pems, err := cert.PEM()
+		assert.Nil(t, err)
+
+		cert2, err := CertificateFromPEM(pems)
+		assert.Nil(t, err)
+		assert.True(t, cert.Equals(*cert2))
+
+		fingerprints, err := cert.GetFingerprints()
+		assert.Nil(t, err)
+		fingerprints2, err := cert2.GetFingerprints()
+		assert.Nil(t, err)
+		assert.Equal(t, fingerprints, fingerprints2)
+	}
+
+	_, err := CertificateFromPEM("")
+	assert.Error(t, err)
+
+	_, err = CertificateFromPEM("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n")
+	assert.Error(t, err)
+}
+
+func TestCertificateFromX509(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	cert, err := GenerateCertificate(sk)
+	assert.Nil(t, err)
+
+	cert2, err := CertificateFromX509(cert.PrivateKey(), cert.X509Certificate())
+	assert.Nil(t, err)
+	assert.True(t, cert.Equals(*cert2))
+
+	otherSK, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	_, err = CertificateFromX509(otherSK, cert.X509Certificate())
+	assert.Error(t, err)
+
+	_, err = CertificateFromX509(sk, nil)
+	assert.Error(t, err)
+
+	// The same certificate can be shared by multiple PeerConnections.
+	for i := 0; i < 2; i++ {
+		pc, err := NewPeerConnection(Configuration{Certificates: []Certificate{*cert2}})
+		assert.Nil(t, err)
+		assert.True(t, pc.configuration.Certificates[0].Equals(*cert))
+		assert.Nil(t, pc.Close())
+	}
+}
+
+func TestCertificateGetFingerprints(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	cert, err := GenerateCertificate(sk)
+	assert.Nil(t, err)
+
+	fingerprints, err := cert.GetFingerprints()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(fingerprints))
+
+	expected := map[string]int{"sha-256": 32, "sha-384": 48, "sha-512": 64}
+	for _, fp := range fingerprints {
+		size, ok := expected[fp.Algorithm]
+		assert.True(t, ok, fp.Algorithm)
+		assert.Equal(t, size*3-1, len(fp.Value), fp.Algorithm)
+		delete(expected, fp.Algorithm)
+	}
+}
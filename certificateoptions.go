@@ -0,0 +1,97 @@
+// +build !js
+
+package webrtc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// CertificateKeyType is the type of the private key generated for a Certificate.
+type CertificateKeyType int
+
+const (
+	// CertificateKeyTypeECDSAP256 generates an ECDSA key on the P-256 curve.
+	CertificateKeyTypeECDSAP256 CertificateKeyType = iota + 1
+
+	// CertificateKeyTypeRSA generates an RSA key of CertificateOptions.RSABits.
+	CertificateKeyTypeRSA
+)
+
+const (
+	defaultCertificateValidity = 30 * 24 * time.Hour
+	defaultCertificateRSABits  = 2048
+)
+
+func (t CertificateKeyType) String() string {
+	switch t {
+	case CertificateKeyTypeECDSAP256:
+		return "ecdsa-p256"
+	case CertificateKeyTypeRSA:
+		return "rsa"
+	default:
+		return unknownStr
+	}
+}
+
+// CertificateOptions configures the certificates created by
+// GenerateCertificateWithOptions. Zero values select the defaults used by
+// GenerateCertificate.
+type CertificateOptions struct {
+	// KeyType is the type of the generated private key, it defaults to
+	// CertificateKeyTypeECDSAP256.
+	KeyType CertificateKeyType
+
+	// RSABits is the size of RSA keys, it defaults to 2048.
+	RSABits int
+
+	// Validity is how long the certificate is valid for, it defaults to 30 days.
+	Validity time.Duration
+
+	// Subject is the subject of the certificate, it defaults to a random
+	// common name.
+	Subject pkix.Name
+}
+
+func (o CertificateOptions) validity() time.Duration {
+	if o.Validity <= 0 {
+		return defaultCertificateValidity
+	}
+	return o.Validity
+}
+
+func (o CertificateOptions) generateKey() (crypto.PrivateKey, error) {
+	switch o.KeyType {
+	case 0, CertificateKeyTypeECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case CertificateKeyTypeRSA:
+		bits := o.RSABits
+		if bits == 0 {
+			bits = defaultCertificateRSABits
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return nil, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
+	}
+}
+
+// GenerateCertificateWithOptions creates a private key and a self-signed
+// X.509 certificate as configured by options.
+func GenerateCertificateWithOptions(options CertificateOptions) (*Certificate, error) {
+	sk, err := options.generateKey()
+	if err != nil {
+		if _, ok := err.(*rtcerr.NotSupportedError); ok {
+			return nil, err
+		}
+		return nil, &rtcerr.UnknownError{Err: err}
+	}
+
+	return generateCertificate(sk, options.validity(), options.Subject)
+}
@@ -0,0 +1,54 @@
+// +build !js
+
+package webrtc
+
+import (
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificateKeyType_String(t *testing.T) {
+	testCases := []struct {
+		keyType        CertificateKeyType
+		expectedString string
+	}{
+		{CertificateKeyType(Unknown), unknownStr},
+		{CertificateKeyTypeECDSAP256, "ecdsa-p256"},
+		{CertificateKeyTypeRSA, "rsa"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.keyType.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestGenerateCertificateWithOptions(t *testing.T) {
+	cert, err := GenerateCertificateWithOptions(CertificateOptions{})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, cert.x509Cert.Subject.CommonName)
+	assert.Equal(t, defaultCertificateValidity, cert.x509Cert.NotAfter.Sub(cert.x509Cert.NotBefore))
+
+	cert, err = GenerateCertificateWithOptions(CertificateOptions{
+		KeyType:  CertificateKeyTypeRSA,
+		RSABits:  1024,
+		Validity: time.Hour,
+		Subject:  pkix.Name{CommonName: "server.example.com"},
+	})
+	assert.Nil(t, err)
+	sk, ok := cert.privateKey.(*rsa.PrivateKey)
+	assert.True(t, ok)
+	assert.Equal(t, 1024, sk.N.BitLen())
+	assert.Equal(t, time.Hour, cert.x509Cert.NotAfter.Sub(cert.x509Cert.NotBefore))
+	assert.Equal(t, "server.example.com", cert.x509Cert.Subject.CommonName)
+
+	_, err = GenerateCertificateWithOptions(CertificateOptions{KeyType: CertificateKeyType(42)})
+	assert.Error(t, err)
+}
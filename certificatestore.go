@@ -0,0 +1,95 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// CertificateStore hands out a certificate that is replaced by a freshly
+// generated one once it gets close to its expiry. Set it with
+// SettingEngine.SetCertificateStore so that PeerConnections created without
+// Configuration.Certificates use the current certificate. Existing
+// PeerConnections keep the certificate they were created with.
+type CertificateStore struct {
+	mu sync.Mutex
+
+	options      CertificateOptions
+	rotateBefore time.Duration
+	current      *Certificate
+
+	onRotateHandler func(Certificate)
+}
+
+// NewCertificateStore creates a CertificateStore generating certificates with
+// options. Certificates are rotated rotateBefore their expiry, which has to be
+// shorter than the validity of the certificates.
+func NewCertificateStore(options CertificateOptions, rotateBefore time.Duration) (*CertificateStore, error) {
+	if rotateBefore < 0 || rotateBefore >= options.validity() {
+		return nil, &rtcerr.RangeError{Err: ErrCertificateRotation}
+	}
+
+	current, err := GenerateCertificateWithOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateStore{
+		options:      options,
+		rotateBefore: rotateBefore,
+		current:      current,
+	}, nil
+}
+
+// OnRotate sets a handler that is fired with the new certificate every time
+// the store rotates, to persist it for example.
+func (s *CertificateStore) OnRotate(f func(Certificate)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRotateHandler = f
+}
+
+// Certificate returns the current certificate, it is rotated first if it
+// expires within the rotation window of the store.
+func (s *CertificateStore) Certificate() (Certificate, error) {
+	s.mu.Lock()
+	if time.Now().Before(s.current.Expires().Add(-s.rotateBefore)) {
+		defer s.mu.Unlock()
+		return *s.current, nil
+	}
+
+	certificate, err := s.rotate()
+	if err != nil {
+		return Certificate{}, err
+	}
+	return *certificate, nil
+}
+
+// Rotate replaces the current certificate by a newly generated one.
+func (s *CertificateStore) Rotate() error {
+	s.mu.Lock()
+	_, err := s.rotate()
+	return err
+}
+
+// rotate requires the caller holds the lock, it is released before the
+// OnRotate handler is fired
+func (s *CertificateStore) rotate() (*Certificate, error) {
+	certificate, err := GenerateCertificateWithOptions(s.options)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	s.current = certificate
+	hdlr := s.onRotateHandler
+	s.mu.Unlock()
+
+	if hdlr != nil {
+		hdlr(*certificate)
+	}
+	return certificate, nil
+}
@@ -0,0 +1,63 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificateStore(t *testing.T) {
+	_, err := NewCertificateStore(CertificateOptions{Validity: time.Hour}, time.Hour)
+	assert.Error(t, err)
+
+	store, err := NewCertificateStore(CertificateOptions{Validity: time.Hour}, time.Minute)
+	assert.Nil(t, err)
+
+	rotated := []Certificate{}
+	store.OnRotate(func(c Certificate) {
+		rotated = append(rotated, c)
+	})
+
+	first, err := store.Certificate()
+	assert.Nil(t, err)
+	again, err := store.Certificate()
+	assert.Nil(t, err)
+	assert.True(t, first.Equals(again))
+	assert.Equal(t, 0, len(rotated))
+
+	// New PeerConnections use the current certificate
+	s := SettingEngine{}
+	s.SetCertificateStore(store)
+	api := NewAPI(WithSettingEngine(s))
+
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.Nil(t, err)
+	assert.True(t, pc.configuration.Certificates[0].Equals(first))
+
+	// Entering the rotation window replaces the certificate, existing
+	// PeerConnections keep theirs
+	expiring, x509Cert := *store.current, *store.current.x509Cert
+	x509Cert.NotAfter = time.Now().Add(30 * time.Second)
+	expiring.x509Cert = &x509Cert
+	store.current = &expiring
+
+	second, err := store.Certificate()
+	assert.Nil(t, err)
+	assert.False(t, first.Equals(second))
+	assert.Equal(t, 1, len(rotated))
+	assert.True(t, rotated[0].Equals(second))
+
+	pc2, err := api.NewPeerConnection(Configuration{})
+	assert.Nil(t, err)
+	assert.True(t, pc2.configuration.Certificates[0].Equals(second))
+	assert.True(t, pc.configuration.Certificates[0].Equals(first))
+
+	assert.Nil(t, store.Rotate())
+	assert.Equal(t, 2, len(rotated))
+
+	assert.Nil(t, pc.Close())
+	assert.Nil(t, pc2.Close())
+}
@@ -25,6 +25,9 @@ type Configuration struct {
 	// PeerIdentity sets the target peer identity for the PeerConnection.
 	// The PeerConnection will not establish a connection to a remote peer
 	// unless it can be successfully authenticated with the provided name.
+	// There is no identity provider support, the name has to be the common
+	// name, or a DNS name, email address or URI of the remote certificate.
+	// It requires DTLS, SDES keying and plain RTP fail while it is set.
 	PeerIdentity string
 
 	// Certificates describes a set of certificates that the PeerConnection
@@ -0,0 +1,208 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// readDeadline signals when the deadline set with SetReadDeadline passes. Like with net.Conn a
+// deadline applies to future reads and to blocked reads. The zero value has no deadline
+type readDeadline struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	done  chan struct{}
+}
+
+// set replaces the deadline. A zero value disables the deadline and a time in the past expires it immediately
+func (d *readDeadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done == nil {
+		d.done = make(chan struct{})
+	}
+
+	if d.timer != nil && !d.timer.Stop() {
+		// The timer already fired, wait for done to be closed so it isn't closed twice
+		<-d.done
+	}
+	d.timer = nil
+
+	expired := false
+	select {
+	case <-d.done:
+		expired = true
+	default:
+	}
+
+	if t.IsZero() {
+		if expired {
+			d.done = make(chan struct{})
+		}
+		return
+	}
+
+	if dur := time.Until(t); dur > 0 {
+		if expired {
+			d.done = make(chan struct{})
+		}
+		done := d.done
+		d.timer = time.AfterFunc(dur, func() {
+			close(done)
+		})
+		return
+	}
+
+	if !expired {
+		close(d.done)
+	}
+}
+
+// wait returns a channel that is closed when the deadline passes. The channel is kept until then,
+// so a deadline set later still closes it
+func (d *readDeadline) wait() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done == nil {
+		d.done = make(chan struct{})
+	}
+	return d.done
+}
+
+type interruptibleReadResult struct {
+	n   int
+	err error
+}
+
+// interruptibleReader runs a blocking read in a worker so a Read can be abandoned when a deadline
+// passes or a context is done. An abandoned read keeps running and its packet is returned by the
+// next Read, so nothing is lost
+type interruptibleReader struct {
+	mu       sync.Mutex
+	read     func([]byte) (int, error)
+	buf      []byte
+	running  bool
+	inFlight bool
+	// failed is closed when the read in flight fails, other Reads waiting for it start a new one
+	failed chan struct{}
+
+	requests  chan struct{}
+	results   chan interruptibleReadResult
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newInterruptibleReader(read func([]byte) (int, error)) *interruptibleReader {
+	return &interruptibleReader{
+		read:     read,
+		requests: make(chan struct{}, 1),
+		results:  make(chan interruptibleReadResult, 1),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Read reads into b. It returns ErrDeadlineExceeded when deadline is closed and ctx.Err() when ctx is done
+func (r *interruptibleReader) Read(b []byte, deadline <-chan struct{}, ctx context.Context) (int, error) {
+	ctxDone := ctx.Done()
+
+	for {
+		select {
+		case <-deadline:
+			return 0, ErrDeadlineExceeded
+		case <-ctxDone:
+			return 0, ctx.Err()
+		case <-r.closed:
+			// The owner makes reads fail once closed, no worker is needed
+			return r.read(b)
+		default:
+		}
+
+		failed := r.request()
+
+		select {
+		case result := <-r.results:
+			return r.receive(b, result)
+		case <-failed:
+			// Another Read got the error, try again
+		case <-r.closed:
+			return r.read(b)
+		case <-deadline:
+			return 0, ErrDeadlineExceeded
+		case <-ctxDone:
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// request makes sure the worker has a read in flight. The returned channel is closed if it fails
+func (r *interruptibleReader) request() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.inFlight {
+		if !r.running {
+			if r.buf == nil {
+				r.buf = make([]byte, receiveMTU)
+			}
+			r.running = true
+			go r.loop()
+		}
+		if r.failed == nil {
+			r.failed = make(chan struct{})
+		}
+		r.inFlight = true
+		r.requests <- struct{}{}
+	}
+	return r.failed
+}
+
+// receive hands the result of the read in flight to b
+func (r *interruptibleReader) receive(b []byte, result interruptibleReadResult) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case result.err != nil:
+		// The worker stops after an error
+		r.inFlight, r.running = false, false
+		close(r.failed)
+		r.failed = nil
+		return 0, result.err
+	case len(b) < result.n:
+		// Keep the packet for a Read with a larger buffer
+		r.results <- result
+		return 0, io.ErrShortBuffer
+	}
+
+	r.inFlight = false
+	return copy(b, r.buf[:result.n]), nil
+}
+
+func (r *interruptibleReader) loop() {
+	for {
+		select {
+		case <-r.requests:
+		case <-r.closed:
+			return
+		}
+
+		n, err := r.read(r.buf)
+		r.results <- interruptibleReadResult{n, err}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// close stops the worker once it isn't reading. The owner must make reads fail from now on,
+// usually by closing what is read from
+func (r *interruptibleReader) close() {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+}
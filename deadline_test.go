@@ -0,0 +1,150 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterruptibleReader(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	packets := make(chan []byte)
+	closed := make(chan struct{})
+	reader := newInterruptibleReader(func(b []byte) (int, error) {
+		select {
+		case p := <-packets:
+			return copy(b, p), nil
+		case <-closed:
+			return 0, io.EOF
+		}
+	})
+
+	deadline := &readDeadline{}
+	deadline.set(time.Now().Add(50 * time.Millisecond))
+
+	b := make([]byte, 10)
+	_, err := reader.Read(b, deadline.wait(), context.Background())
+	assert.Equal(t, ErrDeadlineExceeded, err)
+	netErr, ok := err.(net.Error)
+	assert.True(t, ok)
+	assert.True(t, netErr.Timeout())
+
+	// The abandoned read is still running, its packet must not be lost
+	packets <- []byte{0x01, 0x02}
+	deadline.set(time.Time{})
+
+	_, err = reader.Read(make([]byte, 1), deadline.wait(), context.Background())
+	assert.Equal(t, io.ErrShortBuffer, err)
+
+	n, err := reader.Read(b, deadline.wait(), context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x02}, b[:n])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, err = reader.Read(b, deadline.wait(), ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	// A deadline moved while a read is blocked applies to it
+	deadline.set(time.Now().Add(time.Hour))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		deadline.set(time.Now())
+	}()
+	_, err = reader.Read(b, deadline.wait(), context.Background())
+	assert.Equal(t, ErrDeadlineExceeded, err)
+
+	// A read started without a deadline is interrupted by one set later
+	deadline.set(time.Time{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		deadline.set(time.Now())
+	}()
+	_, err = reader.Read(b, deadline.wait(), context.Background())
+	assert.Equal(t, ErrDeadlineExceeded, err)
+
+	// A Read waiting for the read in flight of another one honours its own context
+	deadline.set(time.Time{})
+	readErr := make(chan error)
+	go func() {
+		_, readErr2 := reader.Read(make([]byte, 10), deadline.wait(), context.Background())
+		readErr <- readErr2
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = reader.Read(b, deadline.wait(), ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	packets <- []byte{0x03}
+	assert.NoError(t, <-readErr)
+
+	go func() {
+		packets <- []byte{0x04}
+	}()
+	n, err = reader.Read(b, deadline.wait(), context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x04}, b[:n])
+	assert.False(t, reader.inFlight)
+
+	close(closed)
+	reader.close()
+	deadline.set(time.Time{})
+	_, err = reader.Read(b, deadline.wait(), context.Background())
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadUnblocksOnStop(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+
+	receiver, err := api.NewRTPReceiver(RTPCodecTypeVideo, &DTLSTransport{})
+	assert.NoError(t, err)
+
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	sender, err := api.NewRTPSender(track, &DTLSTransport{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, receiver.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err = receiver.ReadRTCP()
+	assert.Equal(t, ErrDeadlineExceeded, err)
+	assert.NoError(t, receiver.SetReadDeadline(time.Time{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = sender.ReadRTCPContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	receiverErr, senderErr := make(chan error), make(chan error)
+	go func() {
+		_, readErr := receiver.Read(make([]byte, receiveMTU))
+		receiverErr <- readErr
+	}()
+	go func() {
+		_, readErr := sender.Read(make([]byte, receiveMTU))
+		senderErr <- readErr
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, receiver.Stop())
+	assert.NoError(t, sender.Stop())
+	assert.Equal(t, io.EOF, <-receiverErr)
+	assert.Equal(t, io.EOF, <-senderErr)
+}
@@ -1,5 +1,11 @@
 package webrtc
 
+import (
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
 // DTLSFingerprint specifies the hash function algorithm and certificate
 // fingerprint as described in https://tools.ietf.org/html/rfc4572.
 type DTLSFingerprint struct {
@@ -12,3 +18,86 @@ type DTLSFingerprint struct {
 	// https://tools.ietf.org/html/rfc4572#section-5.
 	Value string `json:"value"`
 }
+
+// fingerprintsFromRemoteSDP returns the fingerprints of the remote
+// certificate. Media sections without a fingerprint inherit the session level
+// ones, which are ignored if every media section overrides them. All media
+// sections that end up with fingerprints must describe the same certificate
+// since they share one DTLSTransport.
+func fingerprintsFromRemoteSDP(sessionDescription *sdp.SessionDescription) ([]DTLSFingerprint, error) {
+	sessionFingerprints, err := parseFingerprintAttributes(sessionDescription.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var mediaFingerprints []DTLSFingerprint
+	for _, m := range sessionDescription.MediaDescriptions {
+		current, err := parseFingerprintAttributes(m.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		if len(current) == 0 {
+			current = sessionFingerprints
+		}
+
+		switch {
+		case len(current) == 0:
+			// Rejected or inactive sections may not carry a fingerprint
+		case mediaFingerprints == nil:
+			mediaFingerprints = current
+		case !equalFingerprints(mediaFingerprints, current):
+			return nil, ErrSessionDescriptionConflictingFingerprints
+		}
+	}
+
+	fingerprints := mediaFingerprints
+	if fingerprints == nil {
+		// Without media sections only the session level can describe the certificate
+		fingerprints = sessionFingerprints
+	}
+
+	if len(fingerprints) == 0 {
+		return nil, ErrSessionDescriptionNoFingerprint
+	}
+	return fingerprints, nil
+}
+
+func parseFingerprintAttributes(attributes []sdp.Attribute) ([]DTLSFingerprint, error) {
+	var fingerprints []DTLSFingerprint
+	for _, a := range attributes {
+		if a.Key != "fingerprint" {
+			continue
+		}
+
+		parts := strings.Fields(a.Value)
+		if len(parts) != 2 {
+			return nil, ErrSessionDescriptionInvalidFingerprint
+		}
+		fingerprints = appendFingerprint(fingerprints, DTLSFingerprint{
+			Algorithm: strings.ToLower(parts[0]),
+			Value:     strings.ToLower(parts[1]),
+		})
+	}
+	return fingerprints, nil
+}
+
+func appendFingerprint(fingerprints []DTLSFingerprint, fp DTLSFingerprint) []DTLSFingerprint {
+	for _, f := range fingerprints {
+		if f == fp {
+			return fingerprints
+		}
+	}
+	return append(fingerprints, fp)
+}
+
+func equalFingerprints(a, b []DTLSFingerprint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, fp := range a {
+		if len(appendFingerprint(b, fp)) != len(b) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprintsFromRemoteSDP(t *testing.T) {
+	const (
+		sha256Print = "sha-256 AB:CD"
+		sha512Print = "sha-512 EF:01"
+		otherPrint  = "sha-256 12:34"
+	)
+
+	withFingerprints := func(values ...string) []sdp.Attribute {
+		attributes := []sdp.Attribute{}
+		for _, v := range values {
+			attributes = append(attributes, sdp.NewAttribute("fingerprint", v))
+		}
+		return attributes
+	}
+	media := func(values ...string) *sdp.MediaDescription {
+		return &sdp.MediaDescription{Attributes: withFingerprints(values...)}
+	}
+
+	expectBoth := []DTLSFingerprint{
+		{Algorithm: "sha-256", Value: "ab:cd"},
+		{Algorithm: "sha-512", Value: "ef:01"},
+	}
+
+	testCases := []struct {
+		name     string
+		session  []string
+		media    []*sdp.MediaDescription
+		expected []DTLSFingerprint
+		err      error
+	}{
+		{"session level", []string{sha256Print, sha512Print}, []*sdp.MediaDescription{media(), media()}, expectBoth, nil},
+		{"media level", nil, []*sdp.MediaDescription{media(sha256Print, sha512Print), media(sha512Print, sha256Print)}, expectBoth, nil},
+		{"overridden session level", []string{otherPrint}, []*sdp.MediaDescription{media(sha256Print, sha512Print), media(sha256Print, sha512Print)}, expectBoth, nil},
+		{"session level without media", []string{sha256Print}, nil, expectBoth[:1], nil},
+		{"inherited and repeated", []string{sha256Print}, []*sdp.MediaDescription{media(), media(sha256Print)}, expectBoth[:1], nil},
+		{"conflicting media", nil, []*sdp.MediaDescription{media(sha256Print), media(otherPrint)}, nil, ErrSessionDescriptionConflictingFingerprints},
+		{"conflicting inherited", []string{sha256Print}, []*sdp.MediaDescription{media(), media(otherPrint)}, nil, ErrSessionDescriptionConflictingFingerprints},
+		{"media without fingerprint", nil, []*sdp.MediaDescription{media(), media(sha256Print)}, expectBoth[:1], nil},
+		{"missing", nil, []*sdp.MediaDescription{media()}, nil, ErrSessionDescriptionNoFingerprint},
+		{"invalid", []string{"sha-256"}, nil, nil, ErrSessionDescriptionInvalidFingerprint},
+	}
+
+	for _, testCase := range testCases {
+		fingerprints, err := fingerprintsFromRemoteSDP(&sdp.SessionDescription{
+			Attributes:        withFingerprints(testCase.session...),
+			MediaDescriptions: testCase.media,
+		})
+		assert.Equal(t, testCase.err, err, testCase.name)
+		assert.Equal(t, testCase.expected, fingerprints, testCase.name)
+	}
+}
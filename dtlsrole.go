@@ -37,24 +37,52 @@ func (r DTLSRole) String() string {
 // role can been determined for local connection. The decision is made from the first role we we parse.
 // If no role can be found we return DTLSRoleAuto
 func dtlsRoleFromRemoteSDP(sessionDescription *sdp.SessionDescription) DTLSRole {
-	if sessionDescription == nil {
+	switch setupFromSDP(sessionDescription) {
+	case sdp.ConnectionRoleActive.String():
+		return DTLSRoleServer
+	case sdp.ConnectionRolePassive.String():
+		return DTLSRoleClient
+	default:
 		return DTLSRoleAuto
 	}
+}
+
+// setupFromSDP returns the value of the first setup attribute of the media
+// sections, or an empty string if there is none.
+func setupFromSDP(sessionDescription *sdp.SessionDescription) string {
+	if sessionDescription == nil {
+		return ""
+	}
 
 	for _, mediaSection := range sessionDescription.MediaDescriptions {
-		for _, attribute := range mediaSection.Attributes {
-			if attribute.Key == "setup" {
-				switch attribute.Value {
-				case "active":
-					return DTLSRoleServer
-				case "passive":
-					return DTLSRoleClient
-				default:
-					return DTLSRoleAuto
-				}
-			}
+		if value, ok := mediaSection.Attribute("setup"); ok {
+			return value
 		}
 	}
+	return ""
+}
+
+// answeringDTLSRole determines the local role when answering remoteOffer. An
+// offerer that declares itself active or passive decides the role, otherwise
+// the preferred role is taken, defaulting to the client.
+func answeringDTLSRole(remoteOffer *sdp.SessionDescription, preferred DTLSRole) DTLSRole {
+	if role := dtlsRoleFromRemoteSDP(remoteOffer); role != DTLSRoleAuto {
+		return role
+	}
+	if preferred == DTLSRoleServer {
+		return DTLSRoleServer
+	}
+	return DTLSRoleClient
+}
 
-	return DTLSRoleAuto
+// toConnectionRole returns the setup attribute announcing the local role r.
+func (r DTLSRole) toConnectionRole() sdp.ConnectionRole {
+	switch r {
+	case DTLSRoleClient:
+		return sdp.ConnectionRoleActive
+	case DTLSRoleServer:
+		return sdp.ConnectionRolePassive
+	default:
+		return sdp.ConnectionRoleActpass
+	}
 }
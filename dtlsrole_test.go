@@ -80,3 +80,38 @@ a=setup:%s
 		)
 	}
 }
+
+func TestAnsweringDTLSRole(t *testing.T) {
+	offer := func(setup string) *sdp.SessionDescription {
+		return &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{Attributes: []sdp.Attribute{sdp.NewAttribute("setup", setup)}},
+			},
+		}
+	}
+
+	testCases := []struct {
+		test         string
+		remoteOffer  *sdp.SessionDescription
+		preferred    DTLSRole
+		expectedRole DTLSRole
+	}{
+		{"actpass, no preference", offer("actpass"), DTLSRole(Unknown), DTLSRoleClient},
+		{"actpass, prefer client", offer("actpass"), DTLSRoleClient, DTLSRoleClient},
+		{"actpass, prefer server", offer("actpass"), DTLSRoleServer, DTLSRoleServer},
+		{"no setup, prefer server", &sdp.SessionDescription{}, DTLSRoleServer, DTLSRoleServer},
+		{"active, prefer client", offer("active"), DTLSRoleClient, DTLSRoleServer},
+		{"passive, prefer server", offer("passive"), DTLSRoleServer, DTLSRoleClient},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedRole,
+			answeringDTLSRole(testCase.remoteOffer, testCase.preferred),
+			"TestAnsweringDTLSRole (%s)", testCase.test,
+		)
+	}
+
+	assert.Equal(t, sdp.ConnectionRoleActive, DTLSRoleClient.toConnectionRole())
+	assert.Equal(t, sdp.ConnectionRolePassive, DTLSRoleServer.toConnectionRole())
+	assert.Equal(t, sdp.ConnectionRoleActpass, DTLSRoleAuto.toConnectionRole())
+}
@@ -9,6 +9,7 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -33,17 +34,49 @@ type DTLSTransport struct {
 	remoteCertificate []byte
 	state             DTLSTransportState
 
+	// role is the DTLS role taken in the handshake
+	role DTLSRole
+
+	// peerIdentity is the identity the remote certificate has to carry,
+	// taken from Configuration.PeerIdentity
+	peerIdentity string
+
 	onStateChangeHdlr func(DTLSTransportState)
 
+	// err is the reason the transport failed, it has its own lock so Err
+	// can be called from the OnStateChange handler
+	errLock sync.Mutex
+	err     error
+
 	conn *dtls.Conn
 
-	srtpSession   *srtp.SessionSRTP
-	srtcpSession  *srtp.SessionSRTCP
+	// srtpProtectionProfile is the profile selected by the DTLS handshake
+	srtpProtectionProfile SRTPProtectionProfile
+
+	// sdesKeys are the SRTP keys exchanged in the session descriptions if
+	// SDES keying is used, the DTLS handshake is skipped then
+	sdesKeys *srtp.SessionKeys
+
+	// plainRTP is set if SettingEngine.EnableInsecurePlainRTP is used, media
+	// is then sent without encryption and the DTLS handshake is skipped
+	plainRTP bool
+
+	srtpSession   rtpSession
+	srtcpSession  rtcpSession
 	srtpEndpoint  *mux.Endpoint
 	srtcpEndpoint *mux.Endpoint
 
+	// rtpArrivals is told about every RTP packet as it arrives, before it is read
+	rtpArrivals rtpArrivals
+
 	dtlsMatcher mux.MatchFunc
 
+	// mux demultiplexes a connection given to NewDTLSTransportFromConn,
+	// it is nil when running over an ICETransport
+	mux *mux.Mux
+	// connRole is the local role given to NewDTLSTransportFromConn
+	connRole DTLSRole
+
 	api *API
 }
 
@@ -58,6 +91,10 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 		dtlsMatcher:  mux.MatchDTLS,
 	}
 
+	if api.settingEngine.keyLog != nil {
+		api.settingEngine.LoggerFactory.NewLogger("ortc").Warn("Key logging is enabled, DTLS master secrets and SRTP keys are written in plain text and allow decrypting all traffic of this DTLSTransport")
+	}
+
 	if len(certificates) > 0 {
 		now := time.Now()
 		for _, x509Cert := range certificates {
@@ -66,6 +103,12 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 			}
 			t.certificates = append(t.certificates, x509Cert)
 		}
+	} else if store := api.settingEngine.dtls.CertificateStore; store != nil {
+		certificate, err := store.Certificate()
+		if err != nil {
+			return nil, err
+		}
+		t.certificates = []Certificate{certificate}
 	} else {
 		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 		if err != nil {
@@ -81,6 +124,39 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 	return t, nil
 }
 
+// NewDTLSTransportFromConn creates a new DTLSTransport running directly over conn
+// instead of an ICETransport, for peers that can reach each other without ICE.
+// conn has to preserve packet boundaries, like a connected *net.UDPConn does.
+// SRTP and SCTP are demultiplexed from the same conn, which is closed by Stop.
+// With SettingEngine.EnableInsecurePlainRTP it exchanges plain RTP with endpoints
+// that don't implement ICE or DTLS, see Start, StartSDES keys SRTP for them instead.
+// Without ICE roles to derive it from, role sets the local DTLS role. Start requires
+// DTLSRoleClient or DTLSRoleServer, the remote has to take the other one. Plain RTP
+// and StartSDES have no handshake, DTLSRoleAuto can be passed then.
+// This constructor is part of the ORTC API. It is not
+// meant to be used together with the basic WebRTC API.
+func (api *API) NewDTLSTransportFromConn(conn net.Conn, role DTLSRole, certificates []Certificate) (*DTLSTransport, error) {
+	t, err := api.NewDTLSTransport(nil, certificates)
+	if err != nil {
+		return nil, err
+	}
+
+	t.connRole = role
+	t.mux = mux.NewMux(mux.Config{
+		Conn:          conn,
+		BufferSize:    receiveMTU,
+		LoggerFactory: api.settingEngine.LoggerFactory,
+	})
+	return t, nil
+}
+
+// NewDTLSTransportFromPacketConn is like NewDTLSTransportFromConn for an
+// unconnected conn, it exchanges packets with raddr and drops packets from
+// any other address.
+func (api *API) NewDTLSTransportFromPacketConn(conn net.PacketConn, raddr net.Addr, role DTLSRole, certificates []Certificate) (*DTLSTransport, error) {
+	return api.NewDTLSTransportFromConn(&packetConnWithAddr{PacketConn: conn, raddr: raddr}, role, certificates)
+}
+
 // ICETransport returns the currently-configured *ICETransport or nil
 // if one has not been configured
 func (t *DTLSTransport) ICETransport() *ICETransport {
@@ -106,6 +182,35 @@ func (t *DTLSTransport) OnStateChange(f func(DTLSTransportState)) {
 	t.onStateChangeHdlr = f
 }
 
+// Err returns the reason the DTLSTransport failed, like a failed handshake or a remote
+// certificate that was rejected. It is nil unless the DTLSTransport failed.
+// Unlike the other methods it can be called from the OnStateChange handler.
+func (t *DTLSTransport) Err() error {
+	t.errLock.Lock()
+	defer t.errLock.Unlock()
+	return t.err
+}
+
+// fail records why the transport failed before moving it to the failed state.
+// It requires the caller holds the lock.
+func (t *DTLSTransport) fail(err error) {
+	t.errLock.Lock()
+	t.err = err
+	t.errLock.Unlock()
+	t.onStateChange(DTLSTransportStateFailed)
+}
+
+// Role returns the DTLS role taken in the handshake, DTLSRoleClient or
+// DTLSRoleServer. It is DTLSRoleAuto until the transport has been started.
+func (t *DTLSTransport) Role() DTLSRole {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.role == 0 {
+		return DTLSRoleAuto
+	}
+	return t.role
+}
+
 // State returns the current dtls transport state.
 func (t *DTLSTransport) State() DTLSTransportState {
 	t.lock.RLock()
@@ -146,21 +251,40 @@ func (t *DTLSTransport) startSRTP() error {
 
 	if t.srtpSession != nil && t.srtcpSession != nil {
 		return nil
-	} else if t.conn == nil {
+	} else if t.conn == nil && t.sdesKeys == nil && !t.plainRTP {
 		return fmt.Errorf("the DTLS transport has not started yet")
 	}
 
+	if t.plainRTP {
+		log := t.api.settingEngine.LoggerFactory.NewLogger("ortc")
+		t.srtpSession = newPlainRTPSession(&arrivalConn{t.srtpEndpoint, &t.rtpArrivals}, log)
+		t.srtcpSession = newPlainRTCPSession(t.srtcpEndpoint, log)
+		return nil
+	}
+
+	profile, ok := t.srtpProtectionProfile.srtpProfile()
+	if !ok {
+		return fmt.Errorf("%s was negotiated, but isn't supported", t.srtpProtectionProfile)
+	}
+
 	srtpConfig := &srtp.Config{
-		Profile:       srtp.ProtectionProfileAes128CmHmacSha1_80,
+		Profile:       profile,
 		LoggerFactory: t.api.settingEngine.LoggerFactory,
 	}
 
-	err := srtpConfig.ExtractSessionKeysFromDTLS(t.conn, t.isClient())
-	if err != nil {
+	if t.sdesKeys != nil {
+		srtpConfig.Keys = *t.sdesKeys
+	} else if err := srtpConfig.ExtractSessionKeysFromDTLS(t.conn, t.isClient()); err != nil {
 		return fmt.Errorf("failed to extract sctp session keys: %v", err)
 	}
 
-	srtpSession, err := srtp.NewSessionSRTP(t.srtpEndpoint, srtpConfig)
+	if keyLog := t.api.settingEngine.keyLog; keyLog != nil {
+		if err := keyLog.writeSRTPKeys(t.keyLogFlow(), t.srtpProtectionProfile, srtpConfig.Keys); err != nil {
+			t.api.settingEngine.LoggerFactory.NewLogger("ortc").Warnf("Failed to write SRTP keys to the key log: %v", err)
+		}
+	}
+
+	srtpSession, err := srtp.NewSessionSRTP(&arrivalConn{t.srtpEndpoint, &t.rtpArrivals}, srtpConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start srtp: %v", err)
 	}
@@ -170,12 +294,24 @@ func (t *DTLSTransport) startSRTP() error {
 		return fmt.Errorf("failed to start srtp: %v", err)
 	}
 
-	t.srtpSession = srtpSession
-	t.srtcpSession = srtcpSession
+	t.srtpSession = srtpRTPSession{srtpSession}
+	t.srtcpSession = srtpRTCPSession{srtcpSession}
 	return nil
 }
 
-func (t *DTLSTransport) getSRTPSession() (*srtp.SessionSRTP, error) {
+// keyLogFlow returns the flow the SRTP keys protect: the addresses of the connection given to
+// NewDTLSTransportFromConn, or else the ICE candidate pair selected when the keys are derived
+func (t *DTLSTransport) keyLogFlow() keyLogFlow {
+	if t.mux != nil {
+		return newKeyLogFlow(t.srtpEndpoint.LocalAddr(), t.srtpEndpoint.RemoteAddr())
+	}
+	if pair := t.iceTransport.selectedCandidatePair(); pair != nil {
+		return newKeyLogFlowFromCandidatePair(pair)
+	}
+	return newKeyLogFlow(nil, nil)
+}
+
+func (t *DTLSTransport) getSRTPSession() (rtpSession, error) {
 	t.lock.RLock()
 	if t.srtpSession != nil {
 		t.lock.RUnlock()
@@ -190,7 +326,7 @@ func (t *DTLSTransport) getSRTPSession() (*srtp.SessionSRTP, error) {
 	return t.srtpSession, nil
 }
 
-func (t *DTLSTransport) getSRTCPSession() (*srtp.SessionSRTCP, error) {
+func (t *DTLSTransport) getSRTCPSession() (rtcpSession, error) {
 	t.lock.RLock()
 	if t.srtcpSession != nil {
 		t.lock.RUnlock()
@@ -206,6 +342,10 @@ func (t *DTLSTransport) getSRTCPSession() (*srtp.SessionSRTCP, error) {
 }
 
 func (t *DTLSTransport) isClient() bool {
+	if t.mux != nil {
+		return t.connRole == DTLSRoleClient
+	}
+
 	isClient := true
 	switch t.remoteParameters.Role {
 	case DTLSRoleClient:
@@ -221,8 +361,16 @@ func (t *DTLSTransport) isClient() bool {
 	return isClient
 }
 
-// Start DTLS transport negotiation with the parameters of the remote DTLS transport
+// Start DTLS transport negotiation with the parameters of the remote DTLS transport.
+// A transport created by NewDTLSTransportFromConn takes the role given to the constructor.
+// With SettingEngine.EnableInsecurePlainRTP it skips the handshake and ignores remoteParameters,
+// media is sent as plain RTP then and SCTP can't be used.
 func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
+	// mux is only set by the constructor
+	if t.mux != nil && t.api.settingEngine.insecure.PlainRTP {
+		return t.startPlainRTP()
+	}
+
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
@@ -234,17 +382,28 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		return &rtcerr.InvalidStateError{Err: fmt.Errorf("attempted to start DTLSTransport that is not in new state: %s", t.state)}
 	}
 
-	dtlsEndpoint := t.iceTransport.NewEndpoint(mux.MatchDTLS)
-	t.srtpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTP)
-	t.srtcpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTCP)
+	if t.mux != nil && t.connRole != DTLSRoleClient && t.connRole != DTLSRoleServer {
+		return &rtcerr.InvalidAccessError{Err: ErrDTLSRoleWithoutICE}
+	}
+
+	t.remoteParameters = remoteParameters
+
+	dtlsEndpoint := t.newEndpoint(mux.MatchDTLS)
+	t.srtpEndpoint = t.newEndpoint(mux.MatchSRTP)
+	t.srtcpEndpoint = t.newEndpoint(mux.MatchSRTCP)
 
 	// pion/webrtc#753
 	cert := t.certificates[0]
 
+	srtpProtectionProfiles := t.api.settingEngine.dtls.SRTPProtectionProfiles
+	if len(srtpProtectionProfiles) == 0 {
+		srtpProtectionProfiles = defaultSRTPProtectionProfiles
+	}
+
 	dtlsCofig := &dtls.Config{
 		Certificate:            cert.x509Cert,
 		PrivateKey:             cert.privateKey,
-		SRTPProtectionProfiles: []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80},
+		SRTPProtectionProfiles: dtlsSRTPProtectionProfiles(srtpProtectionProfiles),
 		ClientAuth:             dtls.RequireAnyClientCert,
 		LoggerFactory:          t.api.settingEngine.LoggerFactory,
 		InsecureSkipVerify:     true,
@@ -252,32 +411,179 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 
 	t.onStateChange(DTLSTransportStateConnecting)
 	if t.isClient() {
+		t.role = DTLSRoleClient
+
 		// Assumes the peer offered to be passive and we accepted.
 		dtlsConn, err := dtls.Client(dtlsEndpoint, dtlsCofig)
 		if err != nil {
-			t.onStateChange(DTLSTransportStateFailed)
+			t.fail(err)
 			return err
 		}
 		t.conn = dtlsConn
 	} else {
+		t.role = DTLSRoleServer
+
 		// Assumes we offer to be passive and this is accepted.
 		dtlsConn, err := dtls.Server(dtlsEndpoint, dtlsCofig)
 		if err != nil {
-			t.onStateChange(DTLSTransportStateFailed)
+			t.fail(err)
 			return err
 		}
 		t.conn = dtlsConn
 	}
-	t.onStateChange(DTLSTransportStateConnected)
+
+	if profile, ok := t.conn.SelectedSRTPProtectionProfile(); ok {
+		t.srtpProtectionProfile = SRTPProtectionProfile(profile)
+	}
+
+	if keyLog := t.api.settingEngine.keyLog; keyLog != nil {
+		if err := keyLog.writeDTLSMasterSecret(t.conn); err != nil {
+			t.api.settingEngine.LoggerFactory.NewLogger("ortc").Warnf("Failed to write the DTLS master secret to the key log: %v", err)
+		}
+	}
 
 	// Check the fingerprint if a certificate was exchanged
 	remoteCert := t.conn.RemoteCertificate()
 	if remoteCert == nil {
-		return fmt.Errorf("peer didn't provide certificate via DTLS")
+		err := fmt.Errorf("peer didn't provide certificate via DTLS")
+		t.failVerification(err)
+		return err
 	}
-
 	t.remoteCertificate = remoteCert.Raw
-	return t.validateFingerPrint(remoteParameters, remoteCert)
+
+	if err := t.verifyRemoteCertificate(remoteParameters, remoteCert); err != nil {
+		t.failVerification(err)
+		return err
+	}
+
+	t.onStateChange(DTLSTransportStateConnected)
+	return nil
+}
+
+// verifyRemoteCertificate checks the remote certificate against the
+// fingerprints of the remote parameters, the SettingEngine callback and the
+// configured peer identity, in that order.
+func (t *DTLSTransport) verifyRemoteCertificate(remoteParameters DTLSParameters, remoteCert *x509.Certificate) error {
+	fingerprint, err := t.validateFingerPrint(remoteParameters, remoteCert)
+	if err != nil {
+		return err
+	}
+
+	if verify := t.api.settingEngine.dtls.VerifyPeerCertificate; verify != nil {
+		if err := verify([][]byte{remoteCert.Raw}, fingerprint); err != nil {
+			return err
+		}
+	}
+
+	if t.peerIdentity != "" && !certificateHasIdentity(remoteCert, t.peerIdentity) {
+		return &rtcerr.InvalidAccessError{Err: ErrPeerIdentityMismatch}
+	}
+
+	return nil
+}
+
+// verifiesRemoteCertificate reports if a peer identity or a verification callback is set. Without a
+// DTLS handshake there is no remote certificate to verify, so SDES keying and plain RTP are refused then
+func (t *DTLSTransport) verifiesRemoteCertificate() bool {
+	return t.peerIdentity != "" || t.api.settingEngine.dtls.VerifyPeerCertificate != nil
+}
+
+// failVerification tears down a handshaked connection whose remote
+// certificate was rejected for reason. It requires the caller holds the lock.
+func (t *DTLSTransport) failVerification(reason error) {
+	if err := t.conn.Close(); err != nil {
+		t.api.settingEngine.LoggerFactory.NewLogger("ortc").Warnf("Failed to close DTLS connection: %v", err)
+	}
+	t.fail(reason)
+}
+
+func (t *DTLSTransport) collectStats(collector *statsReportCollector) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	collector.Collecting()
+
+	stats := TransportStats{
+		Timestamp: statsTimestampNow(),
+		Type:      StatsTypeTransport,
+		ID:        "DTLSTransport",
+		DTLSState: t.state,
+	}
+	if t.iceTransport != nil {
+		stats.ICERole = t.iceTransport.Role()
+	}
+	if t.srtpProtectionProfile != 0 {
+		stats.SRTPCipher = t.srtpProtectionProfile.String()
+	}
+
+	collector.Collect(stats.ID, stats)
+}
+
+// StartSDES keys SRTP with keys exchanged out of band instead of starting a DTLS handshake, RFC 4568.
+// It is meant for transports created by NewDTLSTransportFromConn to exchange SRTP with endpoints
+// that implement neither ICE nor DTLS. The state of the DTLSTransport doesn't change and SCTP can't be used.
+// This method is part of the ORTC API. It is not meant to be used together with the basic WebRTC API.
+func (t *DTLSTransport) StartSDES(keys SDESKeys) error {
+	if len(keys.LocalMasterKey) != sdesKeyLength || len(keys.RemoteMasterKey) != sdesKeyLength ||
+		len(keys.LocalMasterSalt) != sdesSaltLength || len(keys.RemoteMasterSalt) != sdesSaltLength {
+		return &rtcerr.InvalidAccessError{Err: ErrSDESKeyLength}
+	}
+
+	return t.startSDES(srtp.SessionKeys{
+		LocalMasterKey:   append([]byte{}, keys.LocalMasterKey...),
+		LocalMasterSalt:  append([]byte{}, keys.LocalMasterSalt...),
+		RemoteMasterKey:  append([]byte{}, keys.RemoteMasterKey...),
+		RemoteMasterSalt: append([]byte{}, keys.RemoteMasterSalt...),
+	})
+}
+
+// startSDES prepares SRTP with keys exchanged in the session descriptions instead of a DTLS handshake, RFC 4568.
+// The state of the DTLSTransport doesn't change, there is no DTLS connection
+func (t *DTLSTransport) startSDES(keys srtp.SessionKeys) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.verifiesRemoteCertificate() {
+		return &rtcerr.InvalidAccessError{Err: ErrCertificateVerificationWithoutDTLS}
+	}
+
+	if err := t.ensureICEConn(); err != nil {
+		return err
+	}
+
+	if t.state != DTLSTransportStateNew || t.sdesKeys != nil {
+		return &rtcerr.InvalidStateError{Err: fmt.Errorf("attempted to start DTLSTransport that is not in new state: %s", t.state)}
+	}
+
+	t.srtpEndpoint = t.newEndpoint(mux.MatchSRTP)
+	t.srtcpEndpoint = t.newEndpoint(mux.MatchSRTCP)
+	t.srtpProtectionProfile = SRTPProtectionProfileAes128CmHmacSha1_80
+	t.sdesKeys = &keys
+	return nil
+}
+
+// startPlainRTP prepares sending and receiving media as plain RTP and RTCP, without any encryption.
+// The state of the DTLSTransport doesn't change, there is no DTLS connection
+func (t *DTLSTransport) startPlainRTP() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.verifiesRemoteCertificate() {
+		return &rtcerr.InvalidAccessError{Err: ErrCertificateVerificationWithoutDTLS}
+	}
+
+	if err := t.ensureICEConn(); err != nil {
+		return err
+	}
+
+	if t.state != DTLSTransportStateNew || t.plainRTP {
+		return &rtcerr.InvalidStateError{Err: fmt.Errorf("attempted to start DTLSTransport that is not in new state: %s", t.state)}
+	}
+
+	t.srtpEndpoint = t.newEndpoint(mux.MatchSRTP)
+	t.srtcpEndpoint = t.newEndpoint(mux.MatchSRTCP)
+	t.plainRTP = true
+	return nil
 }
 
 // Stop stops and closes the DTLSTransport object.
@@ -305,31 +611,76 @@ func (t *DTLSTransport) Stop() error {
 			closeErrs = append(closeErrs, err)
 		}
 	}
+
+	if t.mux != nil {
+		if err := t.mux.Close(); err != nil {
+			closeErrs = append(closeErrs, err)
+		}
+	}
 	t.onStateChange(DTLSTransportStateClosed)
 	return util.FlattenErrs(closeErrs)
 }
 
-func (t *DTLSTransport) validateFingerPrint(remoteParameters DTLSParameters, remoteCert *x509.Certificate) error {
+func (t *DTLSTransport) validateFingerPrint(remoteParameters DTLSParameters, remoteCert *x509.Certificate) (DTLSFingerprint, error) {
 	for _, fp := range remoteParameters.Fingerprints {
-		hashAlgo, err := dtls.HashAlgorithmString(fp.Algorithm)
+		hashAlgo, err := dtls.HashAlgorithmString(strings.ToLower(fp.Algorithm))
 		if err != nil {
-			return err
+			// Ignore algorithms we can't compute, another fingerprint may match
+			continue
 		}
 
 		remoteValue, err := dtls.Fingerprint(remoteCert, hashAlgo)
 		if err != nil {
-			return err
+			return DTLSFingerprint{}, err
 		}
 
 		if strings.EqualFold(remoteValue, fp.Value) {
-			return nil
+			return fp, nil
 		}
 	}
 
-	return errors.New("no matching fingerprint")
+	return DTLSFingerprint{}, &rtcerr.InvalidAccessError{Err: ErrNoMatchingFingerprint}
+}
+
+// certificateHasIdentity reports if identity is the common name or one of the
+// DNS names, email addresses or URIs of the certificate.
+func certificateHasIdentity(cert *x509.Certificate, identity string) bool {
+	if cert.Subject.CommonName == identity {
+		return true
+	}
+	for _, name := range cert.DNSNames {
+		if name == identity {
+			return true
+		}
+	}
+	for _, email := range cert.EmailAddresses {
+		if email == identity {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == identity {
+			return true
+		}
+	}
+	return false
+}
+
+// newEndpoint registers an endpoint on the mux of the connection given to
+// NewDTLSTransportFromConn or else on the mux of the ICETransport.
+func (t *DTLSTransport) newEndpoint(f mux.MatchFunc) *mux.Endpoint {
+	if t.mux != nil {
+		return t.mux.NewEndpoint(f)
+	}
+	return t.iceTransport.NewEndpoint(f)
 }
 
 func (t *DTLSTransport) ensureICEConn() error {
+	if t.mux != nil {
+		// There is no ICE, the connection was given to us
+		return nil
+	}
+
 	if t.iceTransport == nil ||
 		t.iceTransport.State() == ICETransportStateNew {
 		return errors.New("ICE connection not started")
@@ -337,3 +688,30 @@ func (t *DTLSTransport) ensureICEConn() error {
 
 	return nil
 }
+
+// packetConnWithAddr turns a net.PacketConn into a net.Conn exchanging
+// packets with a single remote address.
+type packetConnWithAddr struct {
+	net.PacketConn
+	raddr net.Addr
+}
+
+func (c *packetConnWithAddr) Read(b []byte) (int, error) {
+	for {
+		n, addr, err := c.ReadFrom(b)
+		if err != nil {
+			return n, err
+		}
+		if addr.String() == c.raddr.String() {
+			return n, nil
+		}
+	}
+}
+
+func (c *packetConnWithAddr) Write(b []byte) (int, error) {
+	return c.WriteTo(b, c.raddr)
+}
+
+func (c *packetConnWithAddr) RemoteAddr() net.Addr {
+	return c.raddr
+}
@@ -0,0 +1,121 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/internal/util"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDTLSTransport_FromConn(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	connA, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	connB, err := net.DialUDP("udp4", nil, connA.LocalAddr().(*net.UDPAddr))
+	assert.NoError(t, err)
+
+	// There are no ICE roles to derive the DTLS role from
+	pipeA, pipeB := net.Pipe()
+	auto, err := api.NewDTLSTransportFromConn(pipeA, DTLSRoleAuto, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrDTLSRoleWithoutICE}, auto.Start(DTLSParameters{}))
+	assert.NoError(t, auto.Stop())
+	assert.NoError(t, pipeB.Close())
+
+	dtlsA, err := api.NewDTLSTransportFromPacketConn(connA, connB.LocalAddr(), DTLSRoleClient, nil)
+	assert.NoError(t, err)
+	dtlsB, err := api.NewDTLSTransportFromConn(connB, DTLSRoleServer, nil)
+	assert.NoError(t, err)
+
+	paramsA, err := dtlsA.GetLocalParameters()
+	assert.NoError(t, err)
+	paramsB, err := dtlsB.GetLocalParameters()
+	assert.NoError(t, err)
+
+	sctpA, sctpB := api.NewSCTPTransport(dtlsA), api.NewSCTPTransport(dtlsB)
+
+	start := func(d *DTLSTransport, s *SCTPTransport, remote DTLSParameters, errs chan error) {
+		if err := d.Start(remote); err != nil {
+			errs <- err
+			return
+		}
+		errs <- s.Start(SCTPCapabilities{})
+	}
+	errsA, errsB := make(chan error), make(chan error)
+	go start(dtlsA, sctpA, paramsB, errsA)
+	go start(dtlsB, sctpB, paramsA, errsB)
+	assert.NoError(t, util.FlattenErrs([]error{<-errsA, <-errsB}))
+	assert.Equal(t, DTLSRoleClient, dtlsA.Role())
+	assert.Equal(t, DTLSRoleServer, dtlsB.Role())
+
+	// Data channels
+	channelOpened := make(chan struct{})
+	messageReceived := make(chan struct{})
+	sctpB.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.Equal(t, "ABC", string(msg.Data))
+			close(messageReceived)
+		})
+		close(channelOpened)
+	})
+	channelA, err := api.NewDataChannel(sctpA, &DataChannelParameters{Label: "Foo", ID: 1})
+	assert.NoError(t, err)
+	<-channelOpened
+	assert.NoError(t, channelA.SendText("ABC"))
+	<-messageReceived
+
+	// RTP
+	track, err := NewTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+	sender, err := api.NewRTPSender(track, dtlsA)
+	assert.NoError(t, err)
+	assert.NoError(t, sender.Send(RTPSendParameters{
+		Encodings: RTPEncodingParameters{RTPCodingParameters{SSRC: 1234, PayloadType: DefaultPayloadTypeOpus}},
+	}))
+	receiver, err := api.NewRTPReceiver(RTPCodecTypeAudio, dtlsB)
+	assert.NoError(t, err)
+	assert.NoError(t, receiver.Receive(RTPReceiveParameters{
+		Encodings: RTPDecodingParameters{RTPCodingParameters{SSRC: 1234}},
+	}))
+
+	packetReceived := make(chan struct{})
+	go func() {
+		p, readErr := receiver.Track().ReadRTP()
+		if readErr == nil {
+			assert.Equal(t, uint32(1234), p.SSRC)
+		}
+		close(packetReceived)
+	}()
+	func() {
+		for {
+			select {
+			case <-packetReceived:
+				return
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 960}))
+			}
+		}
+	}()
+
+	assert.NoError(t, sender.Stop())
+	assert.NoError(t, receiver.Stop())
+	assert.NoError(t, sctpA.Stop())
+	assert.NoError(t, sctpB.Stop())
+	assert.NoError(t, dtlsA.Stop())
+	assert.NoError(t, dtlsB.Stop())
+}
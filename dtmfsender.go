@@ -0,0 +1,244 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+const (
+	dtmfDefaultDuration     = 100 * time.Millisecond
+	dtmfMinDuration         = 40 * time.Millisecond
+	dtmfMaxDuration         = 6000 * time.Millisecond
+	dtmfDefaultInterToneGap = 70 * time.Millisecond
+	dtmfMinInterToneGap     = 30 * time.Millisecond
+	dtmfCommaDelay          = 2 * time.Second
+
+	// dtmfPacketInterval is how often a telephone-event packet is sent while a tone is played
+	dtmfPacketInterval = 50 * time.Millisecond
+
+	// dtmfVolume is the power level of the tones in -dBm0, RFC 4733 Section 2.3.4
+	dtmfVolume = 10
+
+	// dtmfEndRetransmissions is how often the final packet of a tone is sent, RFC 4733 Section 2.5.1.4
+	dtmfEndRetransmissions = 3
+
+	dtmfTones = "0123456789*#ABCD"
+)
+
+// DTMFSender sends DTMF tones as telephone-events on the SSRC of an audio RTPSender, RFC 4733
+// https://www.w3.org/TR/webrtc/#rtcdtmfsender
+type DTMFSender struct {
+	sender *RTPSender
+
+	mu                  sync.Mutex
+	toneBuffer          string
+	duration            time.Duration
+	interToneGap        time.Duration
+	playing             bool
+	onToneChangeHandler func(tone string)
+}
+
+// OnToneChange sets an event handler which is called when a tone starts playing.
+// It is called with an empty tone once the tone buffer is empty
+func (d *DTMFSender) OnToneChange(f func(tone string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onToneChangeHandler = f
+}
+
+// ToneBuffer returns the tones that remain to be played
+func (d *DTMFSender) ToneBuffer() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.toneBuffer
+}
+
+// CanInsertDTMF reports if the RTPSender is sending and telephone-event was negotiated with the remote
+func (d *DTMFSender) CanInsertDTMF() bool {
+	_, ok := d.telephoneEvent()
+	return ok
+}
+
+func (d *DTMFSender) telephoneEvent() (*RTPCodec, bool) {
+	select {
+	case <-d.sender.stopCalled:
+		return nil, false
+	default:
+	}
+	if !d.sender.hasSent() {
+		return nil, false
+	}
+
+	d.sender.mu.RLock()
+	defer d.sender.mu.RUnlock()
+	return d.sender.telephoneEvent, d.sender.telephoneEvent != nil
+}
+
+// InsertDTMF replaces the tone buffer with tones and starts playing them if no tone is playing.
+// Tones are 0-9, A-D, * and #, a comma delays the next tone by two seconds. The duration of every
+// tone is clamped to 40ms-6000ms and interToneGap is at least 30ms, zero values select 100ms and 70ms
+func (d *DTMFSender) InsertDTMF(tones string, duration, interToneGap time.Duration) error {
+	if !d.CanInsertDTMF() {
+		return &rtcerr.InvalidStateError{Err: fmt.Errorf("telephone-event was not negotiated or the RTPSender is not sending")}
+	}
+
+	tones = strings.ToUpper(tones)
+	for _, tone := range tones {
+		if tone != ',' && !strings.ContainsRune(dtmfTones, tone) {
+			return &rtcerr.InvalidCharacterError{Err: fmt.Errorf("invalid DTMF tone %q", tone)}
+		}
+	}
+
+	switch {
+	case duration == 0:
+		duration = dtmfDefaultDuration
+	case duration < dtmfMinDuration:
+		duration = dtmfMinDuration
+	case duration > dtmfMaxDuration:
+		duration = dtmfMaxDuration
+	}
+	switch {
+	case interToneGap == 0:
+		interToneGap = dtmfDefaultInterToneGap
+	case interToneGap < dtmfMinInterToneGap:
+		interToneGap = dtmfMinInterToneGap
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.toneBuffer = tones
+	d.duration = duration
+	d.interToneGap = interToneGap
+	if !d.playing && tones != "" {
+		d.playing = true
+		go d.playout()
+	}
+	return nil
+}
+
+// playout plays the tone buffer until it is empty or the RTPSender is stopped
+func (d *DTMFSender) playout() {
+	for {
+		d.mu.Lock()
+		hdlr := d.onToneChangeHandler
+		if d.toneBuffer == "" {
+			d.playing = false
+			d.mu.Unlock()
+
+			if hdlr != nil {
+				hdlr("")
+			}
+			return
+		}
+		tone := d.toneBuffer[0]
+		d.toneBuffer = d.toneBuffer[1:]
+		duration, interToneGap := d.duration, d.interToneGap
+		d.mu.Unlock()
+
+		if hdlr != nil {
+			hdlr(string(tone))
+		}
+
+		delay := interToneGap
+		if tone == ',' {
+			delay = dtmfCommaDelay
+		} else if err := d.playTone(byte(strings.IndexByte(dtmfTones, tone)), duration); err != nil {
+			d.stop()
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-d.sender.stopCalled:
+			d.stop()
+			return
+		}
+	}
+}
+
+func (d *DTMFSender) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.toneBuffer = ""
+	d.playing = false
+}
+
+// playTone sends the packets of a single event, updating its duration every dtmfPacketInterval.
+// Events longer than the 16 bit duration field are split into segments, RFC 4733 Section 2.5.1.3
+func (d *DTMFSender) playTone(event byte, duration time.Duration) error {
+	codec, ok := d.telephoneEvent()
+	if !ok {
+		return fmt.Errorf("telephone-event is not negotiated")
+	}
+
+	d.sender.sendMu.Lock()
+	timestamp := d.sender.currentTimestamp(codec.ClockRate)
+	d.sender.sendMu.Unlock()
+
+	total := uint32(int64(duration) * int64(codec.ClockRate) / int64(time.Second))
+	step := uint32(int64(dtmfPacketInterval) * int64(codec.ClockRate) / int64(time.Second))
+
+	ticker := time.NewTicker(dtmfPacketInterval)
+	defer ticker.Stop()
+
+	payload := make([]byte, 4)
+	marker := true
+	var segmentStart, played uint32
+	for {
+		played += step
+		if played > total {
+			played = total
+		}
+
+		if played-segmentStart > 0xFFFF {
+			// Close the current segment with its maximum duration and continue in a new one
+			binary.BigEndian.PutUint32(payload, uint32(event)<<24|dtmfVolume<<16|0xFFFF)
+			if err := d.sender.sendTelephoneEvent(codec.PayloadType, timestamp+segmentStart, marker, payload); err != nil {
+				return err
+			}
+			marker = false
+			segmentStart += 0xFFFF
+		}
+
+		end := played == total
+		packets := 1
+		flags := uint32(dtmfVolume)
+		if end {
+			packets = dtmfEndRetransmissions
+			flags |= 0x80
+		}
+
+		binary.BigEndian.PutUint32(payload, uint32(event)<<24|flags<<16|(played-segmentStart))
+		for i := 0; i < packets; i++ {
+			if err := d.sender.sendTelephoneEvent(codec.PayloadType, timestamp+segmentStart, marker, payload); err != nil {
+				return err
+			}
+			marker = false
+		}
+		if end {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-d.sender.stopCalled:
+			return fmt.Errorf("RTPSender has been stopped")
+		}
+	}
+}
+
+// dtmfFromTelephoneEvent decodes the payload of a telephone-event packet, ok is false
+// for events that aren't DTMF tones
+func dtmfFromTelephoneEvent(payload []byte) (tone string, end bool, duration uint16, ok bool) {
+	if len(payload) < 4 || int(payload[0]) >= len(dtmfTones) {
+		return "", false, 0, false
+	}
+	return dtmfTones[payload[0] : payload[0]+1], payload[1]&0x80 != 0, binary.BigEndian.Uint16(payload[2:]), true
+}
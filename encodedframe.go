@@ -0,0 +1,88 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+)
+
+// sampleBuilderMaxLate is how many packets Track.ReadSample waits for missing packets of a frame
+const sampleBuilderMaxLate = 50
+
+// EncodedFrame is a complete encoded frame of a Track. An RTPSender passes it to its transform
+// before packetization, an RTPReceiver after depacketization
+// https://w3c.github.io/webrtc-encoded-transform/
+type EncodedFrame struct {
+	// Data is the encoded frame, a transform may modify it or replace it
+	Data []byte
+
+	// Keyframe is set for video frames that can be decoded without previous frames. It is determined
+	// from Data before the transform, so it is unreliable if the remote sends encrypted frames
+	Keyframe bool
+
+	// Timestamp is the RTP timestamp of the frame
+	Timestamp uint32
+
+	// SSRC and PayloadType are the ones the frame is sent or was received with
+	SSRC        uint32
+	PayloadType uint8
+}
+
+// EncodedFrameTransform is called with every frame sent or received, see RTPSender.SetTransform
+// and RTPReceiver.SetTransform. The frame is dropped if it returns an error
+type EncodedFrameTransform func(frame *EncodedFrame) error
+
+// isKeyframe inspects the bitstream of a video frame
+func isKeyframe(codec *RTPCodec, data []byte) bool {
+	if codec == nil || len(data) == 0 {
+		return false
+	}
+
+	switch codec.Name {
+	case VP8:
+		// The P bit of the frame tag is zero for key frames, RFC 6386 Section 9.1
+		return data[0]&0x01 == 0
+	case VP9:
+		// frame_marker, profile, show_existing_frame and frame_type of the uncompressed header
+		if data[0]>>6 != 2 {
+			return false
+		}
+		bit := uint(3)
+		if profile := (data[0]>>5)&0x01 | (data[0]>>3)&0x02; profile == 3 {
+			bit--
+		}
+		return data[0]>>bit&0x01 == 0 && data[0]>>(bit-1)&0x01 == 0
+	case H264:
+		// Look for an IDR NAL unit in the Annex B byte stream
+		for i := 0; i+3 < len(data); i++ {
+			if data[i] == 0 && data[i+1] == 0 && data[i+2] == 1 && data[i+3]&0x1F == 5 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rawDepacketizer is used for codecs whose payload is the frame
+type rawDepacketizer struct{}
+
+func (rawDepacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+// depacketizerForCodec returns the depacketizer ReadSample assembles frames with. Only VP8, Opus
+// and G722 are supported, other codecs can only be read packet by packet
+func depacketizerForCodec(codec *RTPCodec) (rtp.Depacketizer, error) {
+	switch codec.Name {
+	case VP8:
+		return &codecs.VP8Packet{}, nil
+	case Opus:
+		return &codecs.OpusPacket{}, nil
+	case G722:
+		return rawDepacketizer{}, nil
+	}
+	return nil, fmt.Errorf("no depacketizer for codec %s", codec.Name)
+}
@@ -0,0 +1,70 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsKeyframe(t *testing.T) {
+	testCases := []struct {
+		name     string
+		codec    *RTPCodec
+		data     []byte
+		keyframe bool
+	}{
+		{"VP8Keyframe", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000), []byte{0x10, 0x02}, true},
+		{"VP8Interframe", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000), []byte{0x11, 0x02}, false},
+		{"VP9Profile0Keyframe", NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000), []byte{0x82}, true},
+		{"VP9Profile0Interframe", NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000), []byte{0x86}, false},
+		{"VP9ShowExistingFrame", NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000), []byte{0x88}, false},
+		{"VP9Profile3Keyframe", NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000), []byte{0xB0}, true},
+		{"H264IDR", NewRTPH264Codec(DefaultPayloadTypeH264, 90000), []byte{0, 0, 0, 1, 0x67, 0x42, 0, 0, 1, 0x65, 0x88}, true},
+		{"H264NonIDR", NewRTPH264Codec(DefaultPayloadTypeH264, 90000), []byte{0, 0, 0, 1, 0x41, 0x9A}, false},
+		{"Audio", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000), []byte{0x00}, false},
+		{"Empty", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000), nil, false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.keyframe, isKeyframe(testCase.codec, testCase.data), testCase.name)
+	}
+}
+
+func TestDepacketizerForCodec(t *testing.T) {
+	for _, codec := range []*RTPCodec{
+		NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000),
+		NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000),
+		NewRTPG722Codec(DefaultPayloadTypeG722, 8000),
+	} {
+		_, err := depacketizerForCodec(codec)
+		assert.NoError(t, err, codec.Name)
+	}
+
+	_, err := depacketizerForCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
+	assert.Error(t, err)
+}
+
+func TestTrack_WriteRTPWithTransform(t *testing.T) {
+	remote, forward, closeFunc := newFakeForwardPair(t)
+	defer closeFunc()
+
+	p := &rtp.Packet{}
+	assert.NoError(t, remote.ReadRTPInto(p, make([]byte, receiveMTU)))
+	raw, err := p.Marshal()
+	assert.NoError(t, err)
+
+	// Packets would bypass the transform and be sent in the clear
+	sender := forward.activeSenders[0]
+	sender.SetTransform(func(frame *EncodedFrame) error {
+		return nil
+	})
+	assert.Equal(t, ErrWriteRTPWithTransform, forward.WriteRTP(p))
+	_, err = forward.Write(raw)
+	assert.Equal(t, ErrWriteRTPWithTransform, err)
+
+	sender.SetTransform(nil)
+	assert.NoError(t, forward.WriteRTP(p))
+}
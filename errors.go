@@ -34,6 +34,18 @@ var (
 	// chosen to generate a certificate is not supported.
 	ErrPrivateKeyType = errors.New("private key type not supported")
 
+	// ErrCertificateRotation indicates that a CertificateStore would have to
+	// rotate certificates before they are even issued.
+	ErrCertificateRotation = errors.New("certificate rotation must happen within the certificate validity")
+
+	// ErrCertificatePEM indicates that a PEM encoded certificate is missing
+	// either the certificate or the private key block.
+	ErrCertificatePEM = errors.New("pem must contain a certificate and a private key")
+
+	// ErrCertificateKeyMismatch indicates that a private key does not match
+	// the public key of the certificate it is paired with.
+	ErrCertificateKeyMismatch = errors.New("private key does not match certificate")
+
 	// ErrModifyingPeerIdentity indicates that an attempt to modify
 	// PeerIdentity was made after PeerConnection has been initialized.
 	ErrModifyingPeerIdentity = errors.New("peerIdentity cannot be modified")
@@ -83,4 +95,71 @@ var (
 	// ErrIncorrectSDPSemantics indicates that the PeerConnection was configured to
 	// generate SDP Answers with different SDP Semantics than the received Offer
 	ErrIncorrectSDPSemantics = errors.New("offer SDP semantics does not match configuration")
+
+	// ErrSSRCInUse indicates that another RTPSender of the PeerConnection
+	// already sends with the requested SSRC
+	ErrSSRCInUse = errors.New("SSRC is already used by another RTPSender")
+
+	// ErrUnsupportedSRTPProtectionProfile indicates that a SRTP protection profile
+	// isn't implemented, this includes the AEAD-AES-GCM profiles
+	ErrUnsupportedSRTPProtectionProfile = errors.New("unsupported SRTP protection profile")
+
+	// ErrSessionDescriptionNoFingerprint indicates that a remote
+	// SessionDescription using DTLS does not contain a fingerprint
+	ErrSessionDescriptionNoFingerprint = errors.New("could not find fingerprint")
+
+	// ErrSessionDescriptionInvalidFingerprint indicates that a fingerprint
+	// attribute is not formatted as '<hash-func> <fingerprint>'
+	ErrSessionDescriptionInvalidFingerprint = errors.New("invalid fingerprint")
+
+	// ErrSessionDescriptionConflictingFingerprints indicates that the media
+	// sections of a SessionDescription describe different certificates
+	ErrSessionDescriptionConflictingFingerprints = errors.New("conflicting fingerprints between media sections")
+
+	// ErrNoMatchingFingerprint indicates that the remote DTLS certificate
+	// matches none of the fingerprints of the remote SessionDescription
+	ErrNoMatchingFingerprint = errors.New("no matching fingerprint")
+
+	// ErrPeerIdentityMismatch indicates that the remote DTLS certificate
+	// does not carry the identity set in Configuration.PeerIdentity
+	ErrPeerIdentityMismatch = errors.New("remote certificate does not match peer identity")
+
+	// ErrCertificateVerificationWithoutDTLS indicates that a peer identity
+	// or a certificate verification callback is set, but SDES keying or
+	// plain RTP is used, so there is no remote certificate to verify
+	ErrCertificateVerificationWithoutDTLS = errors.New("remote certificate can't be verified without DTLS")
+
+	// ErrAnsweringDTLSRole indicates that the DTLS role set for answering
+	// is neither DTLSRoleClient nor DTLSRoleServer
+	ErrAnsweringDTLSRole = errors.New("answering DTLS role must be client or server")
+
+	// ErrSDESProfile indicates that the transport profile set for SDES
+	// keying is neither RTP/SAVP nor RTP/SAVPF
+	ErrSDESProfile = errors.New("SDES profile must be RTP/SAVP or RTP/SAVPF")
+
+	// ErrAnswerSetupActpass indicates that a remote answer didn't pick a
+	// DTLS role and declared setup:actpass
+	ErrAnswerSetupActpass = errors.New("answer must not use setup:actpass")
+
+	// ErrSDESKeyLength indicates that SDESKeys passed to StartSDES don't
+	// have the key and salt lengths of AES_CM_128_HMAC_SHA1_80
+	ErrSDESKeyLength = errors.New("SDES keys must be 16 bytes and salts 14 bytes long")
+
+	// ErrDTLSRoleWithoutICE indicates that a DTLSTransport without an
+	// ICETransport was started without an explicit DTLS role
+	ErrDTLSRoleWithoutICE = errors.New("DTLS role must be client or server without ICE")
+
+	// ErrWriteRTPWithTransform indicates that packets were written to a
+	// Track with an RTPSender that has a transform, it only transforms samples
+	ErrWriteRTPWithTransform = errors.New("RTP packets can't be written to an RTPSender with a transform")
+
+	// ErrDeadlineExceeded is returned by a read when the deadline set with
+	// SetReadDeadline has passed. It implements net.Error and reports a timeout
+	ErrDeadlineExceeded error = deadlineExceededError{}
 )
+
+type deadlineExceededError struct{}
+
+func (deadlineExceededError) Error() string   { return "read deadline exceeded" }
+func (deadlineExceededError) Timeout() bool   { return true }
+func (deadlineExceededError) Temporary() bool { return true }
@@ -0,0 +1,235 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// fmtpParameters is a parsed a=fmtp line. Keys are stored lowercase since
+// format specific parameter names are case-insensitive.
+type fmtpParameters map[string]string
+
+func parseFmtpParameters(line string) fmtpParameters {
+	parameters := fmtpParameters{}
+	for _, part := range strings.Split(line, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		keyValue := strings.SplitN(part, "=", 2)
+		key := strings.ToLower(strings.TrimSpace(keyValue[0]))
+		if len(keyValue) == 2 {
+			parameters[key] = strings.TrimSpace(keyValue[1])
+		} else {
+			parameters[key] = ""
+		}
+	}
+	return parameters
+}
+
+// equal reports if both parameter sets contain exactly the same entries, regardless of their order
+func (p fmtpParameters) equal(o fmtpParameters) bool {
+	if len(p) != len(o) {
+		return false
+	}
+	for key, value := range p {
+		if otherValue, ok := o[key]; !ok || otherValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (p fmtpParameters) getOrDefault(key, defaultValue string) string {
+	if value, ok := p[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// setFmtpParameter replaces the value of key in an a=fmtp line, preserving the order of the
+// other parameters. If key isn't present it is appended.
+func setFmtpParameter(line, key, value string) string {
+	parts := []string{}
+	found := false
+	for _, part := range strings.Split(line, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if strings.EqualFold(strings.TrimSpace(strings.SplitN(part, "=", 2)[0]), key) {
+			part = key + "=" + value
+			found = true
+		}
+		parts = append(parts, part)
+	}
+
+	if !found {
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, ";")
+}
+
+// fmtpMatch reports if two a=fmtp lines of the codec describe compatible
+// configurations, comparing only the parameters that matter for that codec
+func fmtpMatch(codecName, a, b string) bool {
+	aParameters, bParameters := parseFmtpParameters(a), parseFmtpParameters(b)
+
+	switch {
+	case strings.EqualFold(codecName, H264):
+		return h264FmtpMatch(aParameters, bParameters)
+	case strings.EqualFold(codecName, VP9):
+		return aParameters.getOrDefault("profile-id", "0") == bParameters.getOrDefault("profile-id", "0")
+	case strings.EqualFold(codecName, TelephoneEvent):
+		// The fmtp lists the supported events, every implementation supports the DTMF events 0-15
+		return true
+	default:
+		return aParameters.equal(bParameters)
+	}
+}
+
+// fmtpNegotiate returns the a=fmtp line that should be used in an answer when
+// the local codec matched the remote one
+func fmtpNegotiate(codecName, local, remote string) string {
+	if !strings.EqualFold(codecName, H264) {
+		return local
+	}
+
+	localParameters, remoteParameters := parseFmtpParameters(local), parseFmtpParameters(remote)
+	localProfile, err := parseH264ProfileLevelID(localParameters.getOrDefault("profile-level-id", h264DefaultProfileLevelID))
+	if err != nil {
+		return local
+	}
+	remoteProfile, err := parseH264ProfileLevelID(remoteParameters.getOrDefault("profile-level-id", h264DefaultProfileLevelID))
+	if err != nil {
+		return local
+	}
+
+	// RFC 6184 Section 8.1: when both sides allow level asymmetry each side can keep its level,
+	// otherwise the answer must use the lower of the two
+	negotiated := localProfile
+	if localParameters["level-asymmetry-allowed"] != "1" || remoteParameters["level-asymmetry-allowed"] != "1" {
+		negotiated = localProfile.withLowestLevel(remoteProfile)
+	}
+
+	if _, ok := localParameters["profile-level-id"]; !ok && negotiated == localProfile {
+		return local
+	}
+	return setFmtpParameter(local, "profile-level-id", negotiated.String())
+}
+
+// h264DefaultProfileLevelID is the value implied when profile-level-id is absent, RFC 6184 Section 8.1
+const h264DefaultProfileLevelID = "42000a"
+
+type h264Profile int
+
+const (
+	h264ProfileConstrainedBaseline h264Profile = iota + 1
+	h264ProfileBaseline
+	h264ProfileMain
+	h264ProfileConstrainedHigh
+	h264ProfileHigh
+)
+
+// h264ProfilePatterns maps a profile_idc and a masked profile_iop to a profile.
+// Constraint flags change which profile a profile_idc actually describes, for
+// example 42e0 is Constrained Baseline while 4200 is Baseline.
+var h264ProfilePatterns = []struct {
+	profileIDC byte
+	iopMask    byte
+	iopValue   byte
+	profile    h264Profile
+}{
+	{0x42, 0x4f, 0x40, h264ProfileConstrainedBaseline},
+	{0x4d, 0x8f, 0x80, h264ProfileConstrainedBaseline},
+	{0x58, 0xcf, 0xc0, h264ProfileConstrainedBaseline},
+	{0x42, 0x4f, 0x00, h264ProfileBaseline},
+	{0x58, 0xcf, 0x80, h264ProfileBaseline},
+	{0x4d, 0xaf, 0x00, h264ProfileMain},
+	{0x64, 0xff, 0x00, h264ProfileHigh},
+	{0x64, 0xff, 0x0c, h264ProfileConstrainedHigh},
+}
+
+type h264ProfileLevelID struct {
+	profile    h264Profile
+	profileIDC byte
+	profileIOP byte
+	levelIDC   byte
+}
+
+func parseH264ProfileLevelID(value string) (h264ProfileLevelID, error) {
+	raw, err := hex.DecodeString(value)
+	if err != nil || len(raw) != 3 {
+		return h264ProfileLevelID{}, fmt.Errorf("invalid H264 profile-level-id %q", value)
+	}
+
+	for _, pattern := range h264ProfilePatterns {
+		if raw[0] == pattern.profileIDC && raw[1]&pattern.iopMask == pattern.iopValue {
+			return h264ProfileLevelID{
+				profile:    pattern.profile,
+				profileIDC: raw[0],
+				profileIOP: raw[1],
+				levelIDC:   raw[2],
+			}, nil
+		}
+	}
+
+	return h264ProfileLevelID{}, fmt.Errorf("unsupported H264 profile-level-id %q", value)
+}
+
+// isLevel1b handles the special case where level 1b is signaled with
+// constraint_set3_flag instead of its own level_idc for the Baseline, Main and Extended profiles
+func (p h264ProfileLevelID) isLevel1b() bool {
+	return p.levelIDC == 9 || (p.levelIDC == 11 && p.profileIOP&0x10 != 0 &&
+		(p.profileIDC == 0x42 || p.profileIDC == 0x4d || p.profileIDC == 0x58))
+}
+
+// levelOrder returns a value that can be used to compare levels
+func (p h264ProfileLevelID) levelOrder() int {
+	if p.isLevel1b() {
+		return 105
+	}
+	return int(p.levelIDC) * 10
+}
+
+// withLowestLevel keeps the profile of p and uses the lowest level of p and o
+func (p h264ProfileLevelID) withLowestLevel(o h264ProfileLevelID) h264ProfileLevelID {
+	if p.levelOrder() <= o.levelOrder() {
+		return p
+	}
+
+	lowered := p
+	lowered.levelIDC = o.levelIDC
+	if o.isLevel1b() && o.levelIDC == 11 {
+		lowered.profileIOP |= 0x10
+	} else if p.isLevel1b() {
+		lowered.profileIOP &^= 0x10
+	}
+	return lowered
+}
+
+func (p h264ProfileLevelID) String() string {
+	return hex.EncodeToString([]byte{p.profileIDC, p.profileIOP, p.levelIDC})
+}
+
+func h264FmtpMatch(a, b fmtpParameters) bool {
+	if a.getOrDefault("packetization-mode", "0") != b.getOrDefault("packetization-mode", "0") {
+		return false
+	}
+
+	aProfile, err := parseH264ProfileLevelID(a.getOrDefault("profile-level-id", h264DefaultProfileLevelID))
+	if err != nil {
+		return false
+	}
+	bProfile, err := parseH264ProfileLevelID(b.getOrDefault("profile-level-id", h264DefaultProfileLevelID))
+	if err != nil {
+		return false
+	}
+
+	return aProfile.profile == bProfile.profile
+}
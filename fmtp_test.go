@@ -0,0 +1,69 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFmtpMatch(t *testing.T) {
+	testCases := []struct {
+		name  string
+		codec string
+		a, b  string
+		match bool
+	}{
+		{"Empty", VP8, "", "", true},
+		{"ParameterOrder", Opus, "minptime=10;useinbandfec=1", "useinbandfec=1; minptime=10", true},
+		{"ParameterMismatch", Opus, "minptime=10;useinbandfec=1", "minptime=10", false},
+		{"H264SameProfileDifferentLevel", H264, "packetization-mode=1;profile-level-id=42e01f", "profile-level-id=42e015;packetization-mode=1", true},
+		{"H264ConstrainedBaselineAliases", H264, "packetization-mode=1;profile-level-id=42e01f", "packetization-mode=1;profile-level-id=4de01f", true},
+		{"H264BaselineIsNotConstrainedBaseline", H264, "packetization-mode=1;profile-level-id=42001f", "packetization-mode=1;profile-level-id=42e01f", false},
+		{"H264PacketizationMode", H264, "packetization-mode=1;profile-level-id=42e01f", "profile-level-id=42e01f", false},
+		{"H264DefaultPacketizationMode", H264, "packetization-mode=0;profile-level-id=42e01f", "profile-level-id=42e01f", true},
+		{"H264InvalidProfile", H264, "profile-level-id=zz", "profile-level-id=zz", false},
+		{"VP9DefaultProfile", VP9, "", "profile-id=0", true},
+		{"VP9DifferentProfile", VP9, "profile-id=0", "profile-id=2", false},
+		{"TelephoneEventDifferentEvents", TelephoneEvent, "0-15", "0-16", true},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.match, fmtpMatch(testCase.codec, testCase.a, testCase.b), testCase.name)
+	}
+}
+
+func TestFmtpNegotiate(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		codec                 string
+		local, remote, answer string
+	}{
+		{"NonH264KeepsLocal", Opus, "minptime=10;useinbandfec=1", "useinbandfec=1", "minptime=10;useinbandfec=1"},
+		{"H264LevelDown", H264,
+			"packetization-mode=1;profile-level-id=42e01f",
+			"packetization-mode=1;profile-level-id=42e015",
+			"packetization-mode=1;profile-level-id=42e015"},
+		{"H264RemoteHigherLevel", H264,
+			"packetization-mode=1;profile-level-id=42e015",
+			"packetization-mode=1;profile-level-id=42e01f",
+			"packetization-mode=1;profile-level-id=42e015"},
+		{"H264LevelAsymmetryAllowed", H264,
+			"level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+			"level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e015",
+			"level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f"},
+		{"H264Level1b", H264,
+			"packetization-mode=1;profile-level-id=42e01f",
+			"packetization-mode=1;profile-level-id=42f00b",
+			"packetization-mode=1;profile-level-id=42f00b"},
+		{"H264KeepsLocalProfileBytes", H264,
+			"packetization-mode=1;profile-level-id=42e01f",
+			"packetization-mode=1;profile-level-id=4de00d",
+			"packetization-mode=1;profile-level-id=42e00d"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.answer, fmtpNegotiate(testCase.codec, testCase.local, testCase.remote), testCase.name)
+	}
+}
@@ -25,6 +25,9 @@ type ICETransport struct {
 	onConnectionStateChangeHdlr       func(ICETransportState)
 	onSelectedCandidatePairChangeHdlr func(*ICECandidatePair)
 
+	// selectedPair is the candidate pair currently selected by the agent
+	selectedPair *ICECandidatePair
+
 	state ICETransportState
 
 	gatherer *ICEGatherer
@@ -168,14 +171,22 @@ func (t *ICETransport) OnSelectedCandidatePairChange(f func(*ICECandidatePair))
 }
 
 func (t *ICETransport) onSelectedCandidatePairChange(pair *ICECandidatePair) {
-	t.lock.RLock()
+	t.lock.Lock()
+	t.selectedPair = pair
 	hdlr := t.onSelectedCandidatePairChangeHdlr
-	t.lock.RUnlock()
+	t.lock.Unlock()
 	if hdlr != nil {
 		hdlr(pair)
 	}
 }
 
+// selectedCandidatePair returns the candidate pair currently selected, nil before one is selected
+func (t *ICETransport) selectedCandidatePair() *ICECandidatePair {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.selectedPair
+}
+
 // OnConnectionStateChange sets a handler that is fired when the ICE
 // connection state changes.
 func (t *ICETransport) OnConnectionStateChange(f func(ICETransportState)) {
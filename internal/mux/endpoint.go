@@ -48,14 +48,14 @@ func (e *Endpoint) Write(p []byte) (int, error) {
 	return n, err
 }
 
-// LocalAddr is a stub
+// LocalAddr returns the local address of the muxed connection
 func (e *Endpoint) LocalAddr() net.Addr {
 	return e.mux.nextConn.LocalAddr()
 }
 
-// RemoteAddr is a stub
+// RemoteAddr returns the remote address of the muxed connection
 func (e *Endpoint) RemoteAddr() net.Addr {
-	return e.mux.nextConn.LocalAddr()
+	return e.mux.nextConn.RemoteAddr()
 }
 
 // SetDeadline is a stub
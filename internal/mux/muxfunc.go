@@ -1,10 +1,5 @@
 package mux
 
-import (
-	"bytes"
-	"encoding/binary"
-)
-
 // MatchFunc allows custom logic for mapping packets to an Endpoint
 type MatchFunc func([]byte) bool
 
@@ -79,15 +74,9 @@ func isRTCP(buf []byte) bool {
 		return false
 	}
 
-	var rtcpPacketType uint8
-	r := bytes.NewReader([]byte{buf[1]})
-	if err := binary.Read(r, binary.BigEndian, &rtcpPacketType); err != nil {
-		return false
-	} else if rtcpPacketType >= 192 && rtcpPacketType <= 223 {
-		return true
-	}
-
-	return false
+	// The second byte is the RTCP packet type, RFC 5761 Section 4
+	rtcpPacketType := buf[1]
+	return rtcpPacketType >= 192 && rtcpPacketType <= 223
 }
 
 // MatchSRTP is a MatchFunc that only matches SRTP and not SRTCP
@@ -0,0 +1,126 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/pion/dtls"
+	"github.com/pion/srtp"
+)
+
+// keyLogWriter serializes writes to the key log set with
+// SettingEngine.SetKeyLogWriter, it is shared by all transports of an API.
+type keyLogWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// keyLogFlow identifies the flow the keys of a transport protect by its 5-tuple
+type keyLogFlow struct {
+	protocol      string
+	local, remote string
+}
+
+// newKeyLogFlow returns the flow of a connection, the address of an
+// unknown side is written as '-'
+func newKeyLogFlow(local, remote net.Addr) keyLogFlow {
+	flow := keyLogFlow{protocol: "udp", local: "-", remote: "-"}
+	if local != nil {
+		flow.protocol, flow.local = local.Network(), local.String()
+	}
+	if remote != nil {
+		flow.protocol, flow.remote = remote.Network(), remote.String()
+	}
+	return flow
+}
+
+// newKeyLogFlowFromCandidatePair returns the flow of an ICE candidate pair
+func newKeyLogFlowFromCandidatePair(pair *ICECandidatePair) keyLogFlow {
+	return keyLogFlow{
+		protocol: pair.Local.Protocol.String(),
+		local:    net.JoinHostPort(pair.Local.Address, strconv.Itoa(int(pair.Local.Port))),
+		remote:   net.JoinHostPort(pair.Remote.Address, strconv.Itoa(int(pair.Remote.Port))),
+	}
+}
+
+func (f keyLogFlow) String() string {
+	return fmt.Sprintf("%s %s %s", f.protocol, f.local, f.remote)
+}
+
+// dtlsKeyLogState holds the fields of an exported dtls.State that are written to the key log.
+// They are decoded from the gob encoding of State.MarshalBinary, gob matches fields by name
+type dtlsKeyLogState struct {
+	LocalRandom, RemoteRandom []byte
+	IsClient                  bool
+}
+
+// writeDTLSMasterSecret writes the master secret of a handshaked conn as an NSS key log
+// 'CLIENT_RANDOM <hex client random> <hex master secret>' line, see SettingEngine.SetKeyLogWriter
+func (k *keyLogWriter) writeDTLSMasterSecret(conn *dtls.Conn) error {
+	exported, _, err := conn.Export()
+	if err != nil {
+		return err
+	}
+	raw, err := exported.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	state := dtlsKeyLogState{}
+	if err = gob.NewDecoder(bytes.NewReader(raw)).Decode(&state); err != nil {
+		return err
+	}
+
+	masterSecret, err := dtlsMasterSecret(conn)
+	if err != nil {
+		return err
+	}
+
+	clientRandom := state.RemoteRandom
+	if state.IsClient {
+		clientRandom = state.LocalRandom
+	}
+	return k.write(fmt.Sprintf("CLIENT_RANDOM %x %x\n", clientRandom, masterSecret))
+}
+
+// dtlsMasterSecret returns the master secret of a handshaked conn. Export clones the state
+// without it, so it is read from the state of the connection itself
+func dtlsMasterSecret(conn *dtls.Conn) ([]byte, error) {
+	v := reflect.ValueOf(conn).Elem().FieldByName("state")
+	if v.IsValid() {
+		v = v.FieldByName("masterSecret")
+	}
+	if !v.IsValid() || v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 || v.Len() == 0 {
+		return nil, fmt.Errorf("the DTLS connection has no master secret")
+	}
+	return append([]byte{}, v.Bytes()...), nil
+}
+
+// writeSRTPKeys writes the master key and salt of each direction as a key log comment
+// '# SRTP <protocol> <local address> <remote address> <send|receive> <crypto suite> inline:<base64 key||salt>',
+// see SettingEngine.SetKeyLogWriter. The local keys protect the packets we send, the remote ones the packets we receive.
+func (k *keyLogWriter) writeSRTPKeys(flow keyLogFlow, profile SRTPProtectionProfile, keys srtp.SessionKeys) error {
+	inline := func(key, salt []byte) string {
+		return "inline:" + base64.StdEncoding.EncodeToString(append(append([]byte{}, key...), salt...))
+	}
+
+	return k.write(fmt.Sprintf("# SRTP %s send %s %s\n# SRTP %s receive %s %s\n",
+		flow, profile.cryptoSuite(), inline(keys.LocalMasterKey, keys.LocalMasterSalt),
+		flow, profile.cryptoSuite(), inline(keys.RemoteMasterKey, keys.RemoteMasterSalt)))
+}
+
+func (k *keyLogWriter) write(lines string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	_, err := io.WriteString(k.w, lines)
+	return err
+}
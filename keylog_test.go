@@ -0,0 +1,119 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/srtp"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyLogWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	k := &keyLogWriter{w: buf}
+
+	keys := srtp.SessionKeys{
+		LocalMasterKey:   []byte{0x01, 0x02},
+		LocalMasterSalt:  []byte{0x03},
+		RemoteMasterKey:  []byte{0x0a, 0x0b},
+		RemoteMasterSalt: []byte{0x0c},
+	}
+	flow := newKeyLogFlow(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5000}, &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 6000})
+	assert.NoError(t, k.writeSRTPKeys(flow, SRTPProtectionProfileAes128CmHmacSha1_80, keys))
+	assert.Equal(t, "# SRTP udp 192.0.2.1:5000 [2001:db8::1]:6000 send AES_CM_128_HMAC_SHA1_80 inline:AQID\n"+
+		"# SRTP udp 192.0.2.1:5000 [2001:db8::1]:6000 receive AES_CM_128_HMAC_SHA1_80 inline:CgsM\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, k.writeSRTPKeys(newKeyLogFlow(nil, nil), SRTPProtectionProfileAes128CmHmacSha1_80, keys))
+	assert.True(t, strings.HasPrefix(buf.String(), "# SRTP udp - - send AES_CM_128_HMAC_SHA1_80 "))
+}
+
+func TestPeerConnection_KeyLogWriter(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	newKeyLogAPI := func() (*API, *keyLogWriter) {
+		s := SettingEngine{}
+		s.SetKeyLogWriter(&bytes.Buffer{})
+		api := NewAPI(WithSettingEngine(s))
+		api.mediaEngine.RegisterDefaultCodecs()
+		return api, s.keyLog
+	}
+	// keyLines returns the fields after the label of each key log line once the
+	// master secret and the SRTP keys were written. SRTP lines are labeled by direction
+	keyLines := func(k *keyLogWriter) map[string][]string {
+		for {
+			k.mu.Lock()
+			log := k.w.(*bytes.Buffer).String()
+			k.mu.Unlock()
+
+			if lines := strings.Split(strings.TrimSpace(log), "\n"); len(lines) == 3 {
+				byLabel := map[string][]string{}
+				for _, line := range lines {
+					fields := strings.Fields(line)
+					if fields[0] != "#" {
+						byLabel[fields[0]] = fields[1:]
+						continue
+					}
+
+					// # SRTP <protocol> <local> <remote> <direction> <crypto suite> <key>
+					assert.Equal(t, "SRTP", fields[1])
+					byLabel[fields[5]] = append(append([]string{}, fields[2:5]...), fields[6:]...)
+				}
+				return byLabel
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	offerAPI, offerLog := newKeyLogAPI()
+	answerAPI, answerLog := newKeyLogAPI()
+
+	pcOffer, err := offerAPI.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := answerAPI.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, 0, "audio", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	offerKeys, answerKeys := keyLines(offerLog), keyLines(answerLog)
+
+	// Both sides log the client random and master secret of the same handshake
+	assert.Len(t, offerKeys["CLIENT_RANDOM"], 2)
+	assert.Len(t, offerKeys["CLIENT_RANDOM"][0], 64)
+	assert.Len(t, offerKeys["CLIENT_RANDOM"][1], 96)
+	assert.Equal(t, offerKeys["CLIENT_RANDOM"], answerKeys["CLIENT_RANDOM"])
+
+	// SRTP fields are protocol, local address, remote address, crypto suite and inline key
+	for _, keys := range []map[string][]string{offerKeys, answerKeys} {
+		assert.Len(t, keys["send"], 5)
+		assert.Equal(t, keys["send"][:4], keys["receive"][:4])
+		assert.Equal(t, "AES_CM_128_HMAC_SHA1_80", keys["send"][3])
+		assert.NotEqual(t, keys["send"][4], keys["receive"][4])
+		assert.True(t, strings.HasPrefix(keys["send"][4], "inline:"))
+	}
+
+	// Both sides log the same flow and keys
+	offerFlow, answerFlow := offerKeys["send"], answerKeys["send"]
+	assert.Equal(t, "udp", offerFlow[0])
+	assert.Equal(t, offerFlow[1], answerFlow[2])
+	assert.Equal(t, offerFlow[2], answerFlow[1])
+	assert.Equal(t, offerKeys["send"][4], answerKeys["receive"][4])
+	assert.Equal(t, offerKeys["receive"][4], answerKeys["send"][4])
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -19,11 +19,15 @@ const (
 	DefaultPayloadTypeVP8  = 96
 	DefaultPayloadTypeVP9  = 98
 	DefaultPayloadTypeH264 = 102
+
+	DefaultPayloadTypeTelephoneEvent      = 126
+	DefaultPayloadTypeTelephoneEvent48000 = 110
 )
 
 // MediaEngine defines the codecs supported by a PeerConnection
 type MediaEngine struct {
-	codecs []*RTPCodec
+	codecs             []*RTPCodec
+	payloaderFactories map[string]func() rtp.Payloader
 }
 
 // RegisterCodec registers a codec to a media engine
@@ -37,13 +41,16 @@ func (m *MediaEngine) RegisterCodec(codec *RTPCodec) uint8 {
 func (m *MediaEngine) RegisterDefaultCodecs() {
 	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
 	m.RegisterCodec(NewRTPG722Codec(DefaultPayloadTypeG722, 8000))
+	m.RegisterCodec(NewRTPTelephoneEventCodec(DefaultPayloadTypeTelephoneEvent, 8000))
+	m.RegisterCodec(NewRTPTelephoneEventCodec(DefaultPayloadTypeTelephoneEvent48000, 48000))
 	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
 	m.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
 	m.RegisterCodec(NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
 }
 
 // PopulateFromSDP finds all codecs in a session description and adds them to a MediaEngine, using dynamic
-// payload types and parameters from the sdp.
+// payload types and parameters from the sdp. Codecs that Pion WebRTC doesn't know are registered
+// too, with a Payloader from RegisterPayloaderFactory if one was registered for their MIME type.
 func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 	sdpsd := sdp.SessionDescription{}
 	err := sdpsd.Unmarshal([]byte(sd.SDP))
@@ -51,6 +58,13 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 		return err
 	}
 	for _, md := range sdpsd.MediaDescriptions {
+		kind := NewRTPCodecType(md.MediaName.Media)
+		if kind == 0 {
+			// application sections don't carry RTP codecs
+			continue
+		}
+
+		feedback := rtcpFeedbackFromMediaDescription(md)
 		for _, format := range md.MediaName.Formats {
 			pt, err := strconv.Atoi(format)
 			if err != nil {
@@ -61,33 +75,81 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 			if err != nil {
 				return fmt.Errorf("could not find codec for payload type %d", payloadType)
 			}
-			var codec *RTPCodec
-			clockRate := payloadCodec.ClockRate
-			parameters := payloadCodec.Fmtp
-			switch payloadCodec.Name {
-			case G722:
-				codec = NewRTPG722Codec(payloadType, clockRate)
-			case Opus:
-				codec = NewRTPOpusCodec(payloadType, clockRate)
-			case VP8:
-				codec = NewRTPVP8Codec(payloadType, clockRate)
-				codec.SDPFmtpLine = parameters
-			case VP9:
-				codec = NewRTPVP9Codec(payloadType, clockRate)
-				codec.SDPFmtpLine = parameters
-			case H264:
-				codec = NewRTPH264Codec(payloadType, clockRate)
-				codec.SDPFmtpLine = parameters
-			default:
-				// ignoring other codecs
-				continue
-			}
+
+			codec := m.newCodecFromSDP(kind, payloadCodec)
+			codec.RTCPFeedback = append(codec.RTCPFeedback, feedback[format]...)
+			codec.RTCPFeedback = append(codec.RTCPFeedback, feedback["*"]...)
 			m.RegisterCodec(codec)
 		}
 	}
 	return nil
 }
 
+// RegisterPayloaderFactory registers a function that creates the Payloader for codecs
+// of a MIME type (e.g. "video/H265") added by PopulateFromSDP. It takes precedence over
+// the Payloader Pion WebRTC would pick for the default codecs.
+func (m *MediaEngine) RegisterPayloaderFactory(mimeType string, factory func() rtp.Payloader) {
+	if m.payloaderFactories == nil {
+		m.payloaderFactories = map[string]func() rtp.Payloader{}
+	}
+	m.payloaderFactories[strings.ToLower(mimeType)] = factory
+}
+
+func (m *MediaEngine) newCodecFromSDP(kind RTPCodecType, payloadCodec sdp.Codec) *RTPCodec {
+	var codec *RTPCodec
+	payloadType, clockRate := payloadCodec.PayloadType, payloadCodec.ClockRate
+	switch {
+	case strings.EqualFold(payloadCodec.Name, G722):
+		codec = NewRTPG722Codec(payloadType, clockRate)
+	case strings.EqualFold(payloadCodec.Name, Opus):
+		codec = NewRTPOpusCodec(payloadType, clockRate)
+	case strings.EqualFold(payloadCodec.Name, VP8):
+		codec = NewRTPVP8Codec(payloadType, clockRate)
+	case strings.EqualFold(payloadCodec.Name, VP9):
+		codec = NewRTPVP9Codec(payloadType, clockRate)
+	case strings.EqualFold(payloadCodec.Name, H264):
+		codec = NewRTPH264Codec(payloadType, clockRate)
+	case strings.EqualFold(payloadCodec.Name, TelephoneEvent):
+		codec = NewRTPTelephoneEventCodec(payloadType, clockRate)
+	default:
+		codec = NewRTPCodec(kind, payloadCodec.Name, clockRate, 0, "", payloadType, nil)
+	}
+
+	codec.SDPFmtpLine = payloadCodec.Fmtp
+	if channels, err := strconv.ParseUint(payloadCodec.EncodingParameters, 10, 16); err == nil {
+		codec.Channels = uint16(channels)
+	}
+
+	if factory, ok := m.payloaderFactories[strings.ToLower(codec.MimeType)]; ok {
+		codec.Payloader = factory()
+	}
+	return codec
+}
+
+// rtcpFeedbackFromMediaDescription returns the a=rtcp-fb values of a media section
+// keyed by the format they apply to, "*" applies to all formats
+func rtcpFeedbackFromMediaDescription(md *sdp.MediaDescription) map[string][]RTCPFeedback {
+	feedback := map[string][]RTCPFeedback{}
+	for _, attr := range md.Attributes {
+		if attr.Key != "rtcp-fb" {
+			continue
+		}
+
+		// a=rtcp-fb:<format> <type> [<parameter>]
+		split := strings.SplitN(attr.Value, " ", 3)
+		if len(split) < 2 {
+			continue
+		}
+
+		fb := RTCPFeedback{Type: split[1]}
+		if len(split) == 3 {
+			fb.Parameter = split[2]
+		}
+		feedback[split[0]] = append(feedback[split[0]], fb)
+	}
+	return feedback
+}
+
 func (m *MediaEngine) getCodec(payloadType uint8) (*RTPCodec, error) {
 	for _, codec := range m.codecs {
 		if codec.PayloadType == payloadType {
@@ -99,17 +161,59 @@ func (m *MediaEngine) getCodec(payloadType uint8) (*RTPCodec, error) {
 
 func (m *MediaEngine) getCodecSDP(sdpCodec sdp.Codec) (*RTPCodec, error) {
 	for _, codec := range m.codecs {
-		if codec.Name == sdpCodec.Name &&
-			codec.ClockRate == sdpCodec.ClockRate &&
-			(sdpCodec.EncodingParameters == "" ||
-				strconv.Itoa(int(codec.Channels)) == sdpCodec.EncodingParameters) &&
-			codec.SDPFmtpLine == sdpCodec.Fmtp { // pion/webrtc#43
+		if codecMatchesSDP(codec, sdpCodec) {
 			return codec, nil
 		}
 	}
 	return nil, ErrCodecNotFound
 }
 
+// negotiateCodecs returns the codecs of a kind that match one of the remote codecs,
+// in local order of preference. The returned codecs are copies that carry the
+// negotiated fmtp line, the registered codecs are left untouched.
+func (m *MediaEngine) negotiateCodecs(kind RTPCodecType, remoteCodecs []sdp.Codec) []*RTPCodec {
+	var negotiated []*RTPCodec
+	for _, codec := range m.GetCodecsByKind(kind) {
+		for _, remoteCodec := range remoteCodecs {
+			if !codecMatchesSDP(codec, remoteCodec) {
+				continue
+			}
+
+			c := *codec
+			c.SDPFmtpLine = fmtpNegotiate(codec.Name, codec.SDPFmtpLine, remoteCodec.Fmtp)
+			negotiated = append(negotiated, &c)
+			break
+		}
+	}
+	return negotiated
+}
+
+func codecMatchesSDP(codec *RTPCodec, sdpCodec sdp.Codec) bool {
+	return strings.EqualFold(codec.Name, sdpCodec.Name) &&
+		codec.ClockRate == sdpCodec.ClockRate &&
+		(sdpCodec.EncodingParameters == "" ||
+			strconv.Itoa(int(codec.Channels)) == sdpCodec.EncodingParameters) &&
+		fmtpMatch(codec.Name, codec.SDPFmtpLine, sdpCodec.Fmtp)
+}
+
+// codecsFromMediaDescription returns the codecs a media section offers, in the order of its formats
+func codecsFromMediaDescription(sd *sdp.SessionDescription, md *sdp.MediaDescription) []sdp.Codec {
+	var codecs []sdp.Codec
+	for _, format := range md.MediaName.Formats {
+		pt, err := strconv.Atoi(format)
+		if err != nil {
+			continue
+		}
+
+		codec, err := sd.GetCodecForPayloadType(uint8(pt))
+		if err != nil {
+			continue
+		}
+		codecs = append(codecs, codec)
+	}
+	return codecs
+}
+
 // GetCodecsByKind returns all codecs of a chosen kind in the codecs list
 func (m *MediaEngine) GetCodecsByKind(kind RTPCodecType) []*RTPCodec {
 	var codecs []*RTPCodec
@@ -128,6 +232,9 @@ const (
 	VP8  = "VP8"
 	VP9  = "VP9"
 	H264 = "H264"
+
+	// TelephoneEvent carries DTMF tones next to an audio codec, RFC 4733
+	TelephoneEvent = "telephone-event"
 )
 
 // NewRTPG722Codec is a helper to create a G722 codec
@@ -190,6 +297,19 @@ func NewRTPH264Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 	return c
 }
 
+// NewRTPTelephoneEventCodec is a helper to create a telephone-event codec for the DTMF
+// tones 0-9, *, # and A-D. Its clockrate must match the clockrate of the audio codec
+func NewRTPTelephoneEventCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		TelephoneEvent,
+		clockrate,
+		0,
+		"0-15",
+		payloadType,
+		nil)
+	return c
+}
+
 // RTPCodecType determines the type of a codec
 type RTPCodecType int
 
@@ -3,8 +3,11 @@
 package webrtc
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
 	"github.com/pion/sdp/v2"
 	"github.com/stretchr/testify/assert"
 )
@@ -35,3 +38,105 @@ func TestCodecRegistration(t *testing.T) {
 	_, err := api.mediaEngine.getCodecSDP(sdp.Codec{PayloadType: invalidPT})
 	assert.Equal(t, err, ErrCodecNotFound)
 }
+
+func TestCodecNegotiation(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
+	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+
+	t.Run("FmtpSemantics", func(t *testing.T) {
+		codec, err := m.getCodecSDP(sdp.Codec{
+			Name:      H264,
+			ClockRate: 90000,
+			Fmtp:      "profile-level-id=42001f;packetization-mode=1",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(DefaultPayloadTypeH264), codec.PayloadType)
+
+		_, err = m.getCodecSDP(sdp.Codec{
+			Name:      H264,
+			ClockRate: 90000,
+			Fmtp:      "profile-level-id=42001f;packetization-mode=0",
+		})
+		assert.Equal(t, ErrCodecNotFound, err)
+	})
+
+	t.Run("NegotiatedParameters", func(t *testing.T) {
+		codecs := m.negotiateCodecs(RTPCodecTypeVideo, []sdp.Codec{
+			{Name: VP8, ClockRate: 90000, PayloadType: 100},
+			{Name: H264, ClockRate: 90000, PayloadType: 101, Fmtp: "packetization-mode=1;profile-level-id=42000d"},
+		})
+		if assert.Len(t, codecs, 1) {
+			assert.Equal(t, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42000d", codecs[0].SDPFmtpLine)
+		}
+
+		registered, err := m.getCodec(DefaultPayloadTypeH264)
+		assert.NoError(t, err)
+		assert.Equal(t, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f", registered.SDPFmtpLine)
+
+		assert.Empty(t, m.negotiateCodecs(RTPCodecTypeAudio, []sdp.Codec{{Name: "PCMU", ClockRate: 8000}}))
+	})
+}
+
+func TestPopulateFromSDP(t *testing.T) {
+	const offer = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 111 103 13 126
+c=IN IP4 0.0.0.0
+a=rtpmap:111 opus/48000/2
+a=rtcp-fb:111 transport-cc
+a=fmtp:111 minptime=10;useinbandfec=1;stereo=1
+a=rtpmap:103 ISAC/16000
+a=rtpmap:13 CN/8000
+a=rtpmap:126 telephone-event/8000
+m=video 9 UDP/TLS/RTP/SAVPF 96 97
+c=IN IP4 0.0.0.0
+a=rtpmap:96 VP8/90000
+a=rtcp-fb:96 goog-remb
+a=rtcp-fb:96 nack pli
+a=rtcp-fb:* nack
+a=rtpmap:97 rtx/90000
+a=fmtp:97 apt=96
+m=application 9 DTLS/SCTP 5000
+c=IN IP4 0.0.0.0
+a=sctpmap:5000 webrtc-datachannel 1024
+`
+
+	m := MediaEngine{}
+	m.RegisterPayloaderFactory("audio/ISAC", func() rtp.Payloader {
+		return &codecs.G722Payloader{}
+	})
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: strings.Replace(offer, "\n", "\r\n", -1)}))
+
+	assert.Len(t, m.GetCodecsByKind(RTPCodecTypeAudio), 4)
+	assert.Len(t, m.GetCodecsByKind(RTPCodecTypeVideo), 2)
+
+	opus, err := m.getCodec(111)
+	assert.NoError(t, err)
+	assert.Equal(t, "minptime=10;useinbandfec=1;stereo=1", opus.SDPFmtpLine)
+	assert.Equal(t, []RTCPFeedback{{Type: "transport-cc"}}, opus.RTCPFeedback)
+	assert.IsType(t, &codecs.OpusPayloader{}, opus.Payloader)
+
+	isac, err := m.getCodec(103)
+	assert.NoError(t, err)
+	assert.Equal(t, "audio/ISAC", isac.MimeType)
+	assert.Equal(t, RTPCodecTypeAudio, isac.Type)
+	assert.IsType(t, &codecs.G722Payloader{}, isac.Payloader)
+
+	telephoneEvent, err := m.getCodec(126)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(8000), telephoneEvent.ClockRate)
+	assert.Nil(t, telephoneEvent.Payloader)
+
+	vp8, err := m.getCodec(96)
+	assert.NoError(t, err)
+	assert.Equal(t, []RTCPFeedback{{Type: "goog-remb"}, {Type: "nack", Parameter: "pli"}, {Type: "nack"}}, vp8.RTCPFeedback)
+
+	rtx, err := m.getCodec(97)
+	assert.NoError(t, err)
+	assert.Equal(t, "video/rtx", rtx.MimeType)
+	assert.Equal(t, "apt=96", rtx.SDPFmtpLine)
+	assert.Equal(t, []RTCPFeedback{{Type: "nack"}}, rtx.RTCPFeedback)
+}
@@ -7,8 +7,8 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/base64"
 	"fmt"
-	mathRand "math/rand"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,6 +18,7 @@ import (
 	"github.com/pion/logging"
 	"github.com/pion/rtcp"
 	"github.com/pion/sdp/v2"
+	"github.com/pion/srtp"
 
 	"github.com/pion/webrtc/v2/internal/util"
 	"github.com/pion/webrtc/v2/pkg/rtcerr"
@@ -42,14 +43,19 @@ type PeerConnection struct {
 
 	idpLoginURL *string
 
-	isClosed          bool
-	negotiationNeeded bool
+	isClosed bool
 
 	lastOffer  string
 	lastAnswer string
 
 	rtpTransceivers []*RTPTransceiver
 
+	// cname is announced for all local tracks, RFC 7022
+	cname string
+
+	// sdesCrypto is the local SRTP key if SDES keying is enabled, see SettingEngine.EnableSDESKeying
+	sdesCrypto *sdesCrypto
+
 	// DataChannels
 	dataChannels          map[uint16]*DataChannel
 	dataChannelsOpened    uint32
@@ -60,6 +66,18 @@ type PeerConnection struct {
 	onICEConnectionStateChangeHandler func(ICEConnectionState)
 	onTrackHandler                    func(*Track, *RTPReceiver)
 	onDataChannelHandler              func(*DataChannel)
+	onUnhandledStreamHandler          func(*UnhandledStream)
+	onSSRCCollisionHandler            func(*RTPSender, uint32, uint32)
+
+	// unhandledStreams are the streams passed to onUnhandledStreamHandler that are neither closed nor adopted,
+	// they are closed with the PeerConnection. The receivers of adopted streams are stopped with it
+	unhandledStreams map[*UnhandledStream]struct{}
+	adoptedReceivers map[*RTPReceiver]struct{}
+
+	// closedUnhandledSSRCs are the SSRCs of closed unhandled streams, drainSRTP drops their packets.
+	// closedUnhandledOrder holds them in the order they were closed, the oldest are forgotten first
+	closedUnhandledSSRCs map[unhandledSSRC]struct{}
+	closedUnhandledOrder []unhandledSSRC
 
 	iceGatherer   *ICEGatherer
 	iceTransport  *ICETransport
@@ -96,13 +114,17 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 			ICECandidatePoolSize: 0,
 		},
 		isClosed:           false,
-		negotiationNeeded:  false,
 		lastOffer:          "",
 		lastAnswer:         "",
 		signalingState:     SignalingStateStable,
 		iceConnectionState: ICEConnectionStateNew,
 		connectionState:    PeerConnectionStateNew,
 		dataChannels:       make(map[uint16]*DataChannel),
+		cname:              api.settingEngine.sdpMedia.CNAME,
+
+		unhandledStreams:     map[*UnhandledStream]struct{}{},
+		adoptedReceivers:     map[*RTPReceiver]struct{}{},
+		closedUnhandledSSRCs: map[unhandledSSRC]struct{}{},
 
 		api: api,
 		log: api.settingEngine.LoggerFactory.NewLogger("pc"),
@@ -113,6 +135,21 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		return nil, err
 	}
 
+	if pc.cname == "" {
+		if pc.cname, err = generateCNAME(); err != nil {
+			return nil, err
+		}
+	}
+
+	if api.settingEngine.insecure.PlainRTP {
+		pc.log.Warn("Insecure plain RTP is enabled, media is NOT encrypted and can be read and altered by anyone on the path")
+	} else if api.settingEngine.sdes.Enabled {
+		pc.log.Warn("SDES keying is enabled, media is only as secure as the signaling channel")
+		if pc.sdesCrypto, err = generateSDESCrypto(); err != nil {
+			return nil, err
+		}
+	}
+
 	pc.iceGatherer, err = pc.createICEGatherer()
 	if err != nil {
 		return nil, err
@@ -133,6 +170,7 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	if err != nil {
 		return nil, err
 	}
+	dtlsTransport.peerIdentity = pc.configuration.PeerIdentity
 	pc.dtlsTransport = dtlsTransport
 
 	return pc, nil
@@ -157,6 +195,12 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 			}
 			pc.configuration.Certificates = append(pc.configuration.Certificates, x509Cert)
 		}
+	} else if store := pc.api.settingEngine.dtls.CertificateStore; store != nil {
+		certificate, err := store.Certificate()
+		if err != nil {
+			return err
+		}
+		pc.configuration.Certificates = []Certificate{certificate}
 	} else {
 		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 		if err != nil {
@@ -277,6 +321,74 @@ func (pc *PeerConnection) onTrack(t *Track, r *RTPReceiver) (done chan struct{})
 	return
 }
 
+// OnUnhandledStream sets an event handler which is called when the remote sends RTP or RTCP
+// with an SSRC that isn't announced in the remote description. Without a handler these
+// streams are drained and their packets are dropped.
+func (pc *PeerConnection) OnUnhandledStream(f func(*UnhandledStream)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onUnhandledStreamHandler = f
+}
+
+// onUnhandledStream reads the first packet of the stream and passes it to the handler.
+// Without a handler the stream is left alone, it drops packets once its buffer is full
+func (pc *PeerConnection) onUnhandledStream(s *UnhandledStream) {
+	pc.mu.Lock()
+	hdlr := pc.onUnhandledStreamHandler
+	if hdlr == nil {
+		pc.mu.Unlock()
+		return
+	} else if pc.isClosed {
+		pc.mu.Unlock()
+		if err := s.Close(); err != nil {
+			pc.log.Warnf("Failed to close unhandled stream: %s", err)
+		}
+		return
+	}
+	pc.unhandledStreams[s] = struct{}{}
+	pc.mu.Unlock()
+
+	go func() {
+		b := make([]byte, receiveMTU)
+		n, err := s.Read(b)
+		if err != nil {
+			// The stream was closed with the PeerConnection
+			return
+		}
+
+		s.mu.Lock()
+		s.firstPacket = b[:n]
+		s.peeked = b[:n]
+		s.mu.Unlock()
+		hdlr(s)
+	}()
+}
+
+// OnSSRCCollision sets an event handler which is called when the remote uses the SSRC of
+// one of our RTPSenders. If the SSRC wasn't announced in the local description yet the
+// RTPSender has already moved to newSSRC when it is called. An announced SSRC is kept,
+// the remote could only learn a new one by renegotiating, newSSRC equals oldSSRC then.
+func (pc *PeerConnection) OnSSRCCollision(f func(sender *RTPSender, oldSSRC, newSSRC uint32)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onSSRCCollisionHandler = f
+}
+
+func (pc *PeerConnection) onSSRCCollision(sender *RTPSender, oldSSRC, newSSRC uint32) {
+	pc.mu.RLock()
+	hdlr := pc.onSSRCCollisionHandler
+	pc.mu.RUnlock()
+
+	if oldSSRC == newSSRC {
+		pc.log.Warnf("remote uses SSRC %d of a local sender, keeping it since it was already announced", oldSSRC)
+	} else {
+		pc.log.Warnf("remote uses SSRC %d of a local sender, sending with SSRC %d instead", oldSSRC, newSSRC)
+	}
+	if hdlr != nil {
+		go hdlr(sender, oldSSRC, newSSRC)
+	}
+}
+
 // OnICEConnectionStateChange sets an event handler which is called
 // when an ICE connection state is changed.
 func (pc *PeerConnection) OnICEConnectionStateChange(f func(ICEConnectionState)) {
@@ -439,13 +551,13 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		}
 
 		if len(video) > 0 {
-			if err = pc.addTransceiverSDP(d, "video", iceParams, candidates, sdp.ConnectionRoleActpass, video...); err != nil {
+			if err = pc.addTransceiverSDP(d, "video", iceParams, candidates, sdp.ConnectionRoleActpass, pc.api.mediaEngine.GetCodecsByKind(RTPCodecTypeVideo), video...); err != nil {
 				return SessionDescription{}, err
 			}
 			appendBundle("video")
 		}
 		if len(audio) > 0 {
-			if err = pc.addTransceiverSDP(d, "audio", iceParams, candidates, sdp.ConnectionRoleActpass, audio...); err != nil {
+			if err = pc.addTransceiverSDP(d, "audio", iceParams, candidates, sdp.ConnectionRoleActpass, pc.api.mediaEngine.GetCodecsByKind(RTPCodecTypeAudio), audio...); err != nil {
 				return SessionDescription{}, err
 			}
 			appendBundle("audio")
@@ -453,19 +565,22 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 	} else {
 		for _, t := range pc.GetTransceivers() {
 			midValue := strconv.Itoa(bundleCount)
-			if err = pc.addTransceiverSDP(d, midValue, iceParams, candidates, sdp.ConnectionRoleActpass, t); err != nil {
+			if err = pc.addTransceiverSDP(d, midValue, iceParams, candidates, sdp.ConnectionRoleActpass, pc.api.mediaEngine.GetCodecsByKind(t.kind), t); err != nil {
 				return SessionDescription{}, err
 			}
 			appendBundle(midValue)
 		}
 	}
 
-	midValue := strconv.Itoa(bundleCount)
-	if pc.configuration.SDPSemantics == SDPSemanticsPlanB {
-		midValue = "data"
+	// Data channels need DTLS
+	if !pc.withoutDTLS() {
+		midValue := strconv.Itoa(bundleCount)
+		if pc.configuration.SDPSemantics == SDPSemanticsPlanB {
+			midValue = "data"
+		}
+		pc.addDataMediaSection(d, midValue, iceParams, candidates, sdp.ConnectionRoleActpass)
+		appendBundle(midValue)
 	}
-	pc.addDataMediaSection(d, midValue, iceParams, candidates, sdp.ConnectionRoleActpass)
-	appendBundle(midValue)
 
 	d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue)
 
@@ -592,6 +707,8 @@ func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription)
 		bundleValue += " " + midValue
 	}
 
+	connectionRole := answeringDTLSRole(pc.RemoteDescription().parsed, pc.api.settingEngine.dtls.AnsweringRole).toConnectionRole()
+
 	var t *RTPTransceiver
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
 	detectedPlanB := pc.descriptionIsPlanB(pc.RemoteDescription())
@@ -603,7 +720,20 @@ func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription)
 		}
 
 		if media.MediaName.Media == "application" {
-			pc.addDataMediaSection(d, midValue, iceParams, candidates, sdp.ConnectionRoleActive)
+			if pc.withoutDTLS() {
+				// Data channels need DTLS
+				d.WithMedia((&sdp.MediaDescription{
+					MediaName: sdp.MediaName{
+						Media:   media.MediaName.Media,
+						Port:    sdp.RangedPort{Value: 0},
+						Protos:  media.MediaName.Protos,
+						Formats: media.MediaName.Formats,
+					},
+				}).WithValueAttribute(sdp.AttrKeyMID, midValue))
+				continue
+			}
+
+			pc.addDataMediaSection(d, midValue, iceParams, candidates, connectionRole)
 			appendBundle(midValue)
 			continue
 		}
@@ -643,7 +773,9 @@ func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription)
 				return nil, &rtcerr.TypeError{Err: ErrIncorrectSDPSemantics}
 			}
 		}
-		if err := pc.addTransceiverSDP(d, midValue, iceParams, candidates, sdp.ConnectionRoleActive, mediaTransceivers...); err != nil {
+		// Only answer with the codecs both sides support, using the negotiated parameters
+		codecs := pc.api.mediaEngine.negotiateCodecs(kind, codecsFromMediaDescription(pc.RemoteDescription().parsed, media))
+		if err := pc.addTransceiverSDP(d, midValue, iceParams, candidates, connectionRole, codecs, mediaTransceivers...); err != nil {
 			return nil, err
 		}
 		appendBundle(midValue)
@@ -862,10 +994,20 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	if err := desc.parsed.Unmarshal([]byte(desc.SDP)); err != nil {
 		return err
 	}
+	if desc.Type == SDPTypeAnswer && !pc.withoutDTLS() && setupFromSDP(desc.parsed) == sdp.ConnectionRoleActpass.String() {
+		return &rtcerr.InvalidAccessError{Err: ErrAnswerSetupActpass}
+	}
 	if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
 		return err
 	}
 
+	remoteSSRCs := ssrcsFromSDP(desc.parsed)
+	for _, sender := range pc.GetSenders() {
+		if _, ok := remoteSSRCs[sender.SSRC()]; ok {
+			pc.resolveSSRCCollision(sender)
+		}
+	}
+
 	weOffer := true
 	remoteUfrag := ""
 	remotePwd := ""
@@ -873,12 +1015,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		weOffer = false
 	}
 
-	fingerprint, haveFingerprint := desc.parsed.Attribute("fingerprint")
 	for _, m := range pc.RemoteDescription().parsed.MediaDescriptions {
-		if !haveFingerprint {
-			fingerprint, haveFingerprint = m.Attribute("fingerprint")
-		}
-
 		for _, a := range m.Attributes {
 			switch {
 			case a.IsICECandidate():
@@ -903,16 +1040,40 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		}
 	}
 
-	if !haveFingerprint {
-		return fmt.Errorf("could not find fingerprint")
+	// Our role follows from the answer, which is either the remote one or
+	// the one we generate from the remote offer
+	dtlsRole := dtlsRoleFromRemoteSDP(desc.parsed)
+	if !weOffer {
+		dtlsRole = answeringDTLSRole(desc.parsed, pc.api.settingEngine.dtls.AnsweringRole)
 	}
 
-	parts := strings.Split(fingerprint, " ")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid fingerprint")
+	var sdesKeys *srtp.SessionKeys
+	var fingerprints []DTLSFingerprint
+	plainRTP := pc.api.settingEngine.insecure.PlainRTP
+	if (plainRTP || pc.sdesCrypto != nil) && pc.dtlsTransport.verifiesRemoteCertificate() {
+		return &rtcerr.InvalidAccessError{Err: ErrCertificateVerificationWithoutDTLS}
+	}
+	if plainRTP {
+		if err := validatePlainRTPDescription(desc.parsed); err != nil {
+			return err
+		}
+	} else if pc.sdesCrypto != nil {
+		remoteCrypto, err := remoteSDESCrypto(desc.parsed)
+		if err != nil {
+			return err
+		}
+		sdesKeys = &srtp.SessionKeys{
+			LocalMasterKey:   pc.sdesCrypto.key,
+			LocalMasterSalt:  pc.sdesCrypto.salt,
+			RemoteMasterKey:  remoteCrypto.key,
+			RemoteMasterSalt: remoteCrypto.salt,
+		}
+	} else {
+		var err error
+		if fingerprints, err = fingerprintsFromRemoteSDP(desc.parsed); err != nil {
+			return err
+		}
 	}
-	fingerprint = parts[1]
-	fingerprintHash := parts[0]
 
 	// Create the SCTP transport
 	sctp := pc.api.NewSCTPTransport(pc.dtlsTransport)
@@ -962,14 +1123,20 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 			return
 		}
 
-		// Start the dtls transport
-		err = pc.dtlsTransport.Start(DTLSParameters{
-			Role:         dtlsRoleFromRemoteSDP(desc.parsed),
-			Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
-		})
+		// Start the dtls transport, SDES keying and plain RTP skip the handshake
+		if plainRTP {
+			err = pc.dtlsTransport.startPlainRTP()
+		} else if sdesKeys != nil {
+			err = pc.dtlsTransport.startSDES(*sdesKeys)
+		} else {
+			err = pc.dtlsTransport.Start(DTLSParameters{
+				Role:         dtlsRole,
+				Fingerprints: fingerprints,
+			})
+		}
 		if err != nil {
 			// pion/webrtc#614
-			pc.log.Warnf("Failed to start manager: %s", err)
+			pc.log.Warnf("Failed to start DTLS transport: %s", err)
 			return
 		}
 
@@ -977,13 +1144,25 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 
 		for _, tranceiver := range pc.GetTransceivers() {
 			if tranceiver.Sender != nil {
+				pc.mu.RLock()
+				payloadType := pc.senderPayloadType(tranceiver.Sender)
+				var codecs []*RTPCodec
+				if telephoneEvent := pc.senderTelephoneEvent(tranceiver.Sender); telephoneEvent != nil {
+					codecs = append(codecs, telephoneEvent)
+				}
+				headerExtensions := pc.senderHeaderExtensions(tranceiver.Sender)
+				pc.mu.RUnlock()
+
 				err = tranceiver.Sender.Send(RTPSendParameters{
 					Encodings: RTPEncodingParameters{
 						RTPCodingParameters{
-							SSRC:        tranceiver.Sender.track.SSRC(),
-							PayloadType: tranceiver.Sender.track.PayloadType(),
+							SSRC:        tranceiver.Sender.SSRC(),
+							PayloadType: payloadType,
 						},
-					}})
+					},
+					Codecs:           codecs,
+					HeaderExtensions: headerExtensions,
+				})
 
 				if err != nil {
 					pc.log.Warnf("Failed to start Sender: %s", err)
@@ -993,6 +1172,11 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 
 		go pc.drainSRTP()
 
+		// Data channels need DTLS
+		if sdesKeys != nil || plainRTP {
+			return
+		}
+
 		// Start sctp
 		err = pc.sctpTransport.Start(SCTPCapabilities{
 			MaxMessageSize: 0,
@@ -1050,10 +1234,11 @@ func (pc *PeerConnection) descriptionIsPlanB(desc *SessionDescription) bool {
 // openSRTP opens knows inbound SRTP streams from the RemoteDescription
 func (pc *PeerConnection) openSRTP() {
 	type incomingTrack struct {
-		kind  RTPCodecType
-		label string
-		id    string
-		ssrc  uint32
+		kind     RTPCodecType
+		streamID string
+		id       string
+		ssrc     uint32
+		mid      string
 	}
 	incomingTracks := map[uint32]incomingTrack{}
 
@@ -1066,6 +1251,14 @@ func (pc *PeerConnection) openSRTP() {
 	}
 
 	for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
+		// Unified Plan announces the MediaStream with a=msid, used unless the SSRC has its own msid
+		mediaStreamID, mediaTrackID := "", ""
+		if msid, ok := media.Attribute("msid"); ok {
+			if split := strings.Split(msid, " "); len(split) == 2 {
+				mediaStreamID, mediaTrackID = split[0], split[1]
+			}
+		}
+
 		for _, attr := range media.Attributes {
 
 			codecType := NewRTPCodecType(media.MediaName.Media)
@@ -1081,15 +1274,15 @@ func (pc *PeerConnection) openSRTP() {
 					continue
 				}
 
-				trackID := ""
-				trackLabel := ""
+				trackID := mediaTrackID
+				streamID := mediaStreamID
 				if len(split) == 3 && strings.HasPrefix(split[1], "msid:") {
-					trackLabel = split[1][len("msid:"):]
+					streamID = split[1][len("msid:"):]
 					trackID = split[2]
 				}
 
-				incomingTracks[uint32(ssrc)] = incomingTrack{codecType, trackLabel, trackID, uint32(ssrc)}
-				if trackID != "" && trackLabel != "" {
+				incomingTracks[uint32(ssrc)] = incomingTrack{codecType, streamID, trackID, uint32(ssrc), pc.getMidValue(media)}
+				if trackID != "" && streamID != "" {
 					break // Remote provided Label+ID, we have all the information we need
 				}
 			}
@@ -1097,47 +1290,45 @@ func (pc *PeerConnection) openSRTP() {
 	}
 
 	startReceiver := func(incoming incomingTrack, receiver *RTPReceiver) {
-		err := receiver.Receive(RTPReceiveParameters{
-			Encodings: RTPDecodingParameters{
-				RTPCodingParameters{SSRC: incoming.ssrc},
-			}})
-		if err != nil {
-			pc.log.Warnf("RTPReceiver Receive failed %s", err)
-			return
-		}
-
-		if err = receiver.Track().determinePayloadType(); err != nil {
-			pc.log.Warnf("Could not determine PayloadType for SSRC %d", receiver.Track().SSRC())
-			return
-		}
-
 		pc.mu.RLock()
-		defer pc.mu.RUnlock()
-
 		if pc.currentLocalDescription == nil {
+			pc.mu.RUnlock()
 			pc.log.Warnf("SetLocalDescription not called, unable to handle incoming media streams")
 			return
 		}
+		codecs := pc.negotiatedCodecs(incoming.mid)
+		headerExtensions := pc.negotiatedHeaderExtensions(incoming.kind, incoming.mid)
+		pc.mu.RUnlock()
 
-		sdpCodec, err := pc.currentLocalDescription.parsed.GetCodecForPayloadType(receiver.Track().PayloadType())
+		err := receiver.Receive(RTPReceiveParameters{
+			Encodings: RTPDecodingParameters{
+				RTPCodingParameters{SSRC: incoming.ssrc},
+			},
+			Codecs:           codecs,
+			HeaderExtensions: headerExtensions,
+		})
 		if err != nil {
-			pc.log.Warnf("no codec could be found in RemoteDescription for payloadType %d", receiver.Track().PayloadType())
+			pc.log.Warnf("RTPReceiver Receive failed %s", err)
 			return
 		}
 
-		codec, err := pc.api.mediaEngine.getCodecSDP(sdpCodec)
-		if err != nil {
-			pc.log.Warnf("codec %s in not registered", sdpCodec)
-			return
+		// If the m-line leaves more than one codec to choose from wait for the first packet
+		if receiver.Track().Codec() == nil {
+			if err = receiver.Track().determinePayloadType(); err != nil {
+				pc.log.Warnf("Could not determine PayloadType for SSRC %d: %s", receiver.Track().SSRC(), err)
+				return
+			}
 		}
 
 		receiver.Track().mu.Lock()
 		receiver.Track().id = incoming.id
-		receiver.Track().label = incoming.label
-		receiver.Track().kind = codec.Type
-		receiver.Track().codec = codec
+		receiver.Track().label = incoming.streamID
+		receiver.Track().streamIDs = []string{incoming.streamID}
 		receiver.Track().mu.Unlock()
 
+		pc.mu.RLock()
+		defer pc.mu.RUnlock()
+
 		if pc.onTrackHandler != nil {
 			pc.onTrack(receiver.Track(), receiver)
 		} else {
@@ -1179,10 +1370,191 @@ func (pc *PeerConnection) openSRTP() {
 	}
 }
 
-// drainSRTP pulls and discards RTP/RTCP packets that don't match any SRTP
-// These could be sent to the user, but right now we don't provide an API
-// to distribute orphaned RTCP messages. This is needed to make sure we don't block
-// and provides useful debugging messages
+// negotiatedCodecs returns the codecs that may be received on the media section with the given mid:
+// those listed in both descriptions that are registered in the MediaEngine, with the payload types
+// of the local description. Callers must hold pc.mu
+func (pc *PeerConnection) negotiatedCodecs(mid string) []*RTPCodec {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || pc.currentLocalDescription == nil {
+		return nil
+	}
+
+	var remoteCodecs []sdp.Codec
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if pc.getMidValue(media) == mid {
+			remoteCodecs = codecsFromMediaDescription(remoteDescription.parsed, media)
+			break
+		}
+	}
+
+	var codecs []*RTPCodec
+	localDescription := pc.currentLocalDescription.parsed
+	for _, media := range localDescription.MediaDescriptions {
+		if pc.getMidValue(media) != mid {
+			continue
+		}
+
+		for _, localCodec := range codecsFromMediaDescription(localDescription, media) {
+			codec, err := pc.api.mediaEngine.getCodecSDP(localCodec)
+			if err != nil {
+				continue
+			}
+
+			for _, remoteCodec := range remoteCodecs {
+				if strings.EqualFold(localCodec.Name, remoteCodec.Name) &&
+					localCodec.ClockRate == remoteCodec.ClockRate &&
+					fmtpMatch(localCodec.Name, localCodec.Fmtp, remoteCodec.Fmtp) {
+					c := *codec
+					c.PayloadType = localCodec.PayloadType
+					codecs = append(codecs, &c)
+					break
+				}
+			}
+		}
+		break
+	}
+	return codecs
+}
+
+// localHeaderExtensions returns the header extensions to announce for a media section. An offer contains
+// all supported extensions, an answer the supported extensions of the offer with the IDs of the offer
+func (pc *PeerConnection) localHeaderExtensions(kind RTPCodecType, mid string) []RTPHeaderExtensionParameter {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		var extensions []RTPHeaderExtensionParameter
+		for i, uri := range supportedHeaderExtensions[kind] {
+			extensions = append(extensions, RTPHeaderExtensionParameter{URI: uri, ID: i + 1})
+		}
+		return extensions
+	}
+	return pc.negotiatedHeaderExtensions(kind, mid)
+}
+
+// negotiatedHeaderExtensions returns the supported header extensions of the remote media section with mid
+func (pc *PeerConnection) negotiatedHeaderExtensions(kind RTPCodecType, mid string) []RTPHeaderExtensionParameter {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return nil
+	}
+
+	var extensions []RTPHeaderExtensionParameter
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if pc.getMidValue(media) != mid {
+			continue
+		}
+
+		for _, extension := range headerExtensionsFromMediaDescription(media) {
+			if isSupportedHeaderExtension(kind, extension.URI) {
+				extensions = append(extensions, extension)
+			}
+		}
+		break
+	}
+	return extensions
+}
+
+// senderPayloadType returns the payload type the remote negotiated for the codec of the sender's Track.
+// It falls back to the payload type of the local description and then to the one of the Track.
+// Callers must hold pc.mu
+func (pc *PeerConnection) senderPayloadType(sender *RTPSender) uint8 {
+	if payloadType, ok := pc.senderCodecPayloadType(sender, sender.track.Codec(), true); ok {
+		return payloadType
+	}
+	return sender.track.PayloadType()
+}
+
+// senderTelephoneEvent returns the telephone-event codec the remote negotiated with the clockrate
+// of the sender's Track, or nil if DTMF can't be sent. Callers must hold pc.mu
+func (pc *PeerConnection) senderTelephoneEvent(sender *RTPSender) *RTPCodec {
+	codec := sender.track.Codec()
+	if codec == nil || codec.Type != RTPCodecTypeAudio {
+		return nil
+	}
+
+	telephoneEvent := NewRTPTelephoneEventCodec(0, codec.ClockRate)
+	payloadType, ok := pc.senderCodecPayloadType(sender, telephoneEvent, false)
+	if !ok {
+		return nil
+	}
+	telephoneEvent.PayloadType = payloadType
+	return telephoneEvent
+}
+
+// senderCodecPayloadType finds the payload type of codec in the remote media section the sender is
+// sending in, falling back to the local media section if localFallback is set. Callers must hold pc.mu
+func (pc *PeerConnection) senderCodecPayloadType(sender *RTPSender, codec *RTPCodec, localFallback bool) (uint8, bool) {
+	localDescription, remoteDescription := pc.pendingLocalDescription, pc.RemoteDescription()
+	if localDescription == nil {
+		localDescription = pc.currentLocalDescription
+	}
+	if codec == nil || localDescription == nil || localDescription.parsed == nil || remoteDescription == nil {
+		return 0, false
+	}
+
+	findPayloadType := func(sd *sdp.SessionDescription, md *sdp.MediaDescription) (uint8, bool) {
+		for _, sdpCodec := range codecsFromMediaDescription(sd, md) {
+			if codecMatchesSDP(codec, sdpCodec) {
+				return sdpCodec.PayloadType, true
+			}
+		}
+		return 0, false
+	}
+
+	localMedia := senderMediaDescription(localDescription.parsed, sender)
+	if localMedia == nil {
+		return 0, false
+	}
+
+	mid := pc.getMidValue(localMedia)
+	for _, remoteMedia := range remoteDescription.parsed.MediaDescriptions {
+		if pc.getMidValue(remoteMedia) != mid {
+			continue
+		}
+		if payloadType, ok := findPayloadType(remoteDescription.parsed, remoteMedia); ok {
+			return payloadType, true
+		}
+	}
+
+	if localFallback {
+		return findPayloadType(localDescription.parsed, localMedia)
+	}
+	return 0, false
+}
+
+// senderHeaderExtensions returns the header extensions negotiated for the media section the sender
+// is sending in. Callers must hold pc.mu
+func (pc *PeerConnection) senderHeaderExtensions(sender *RTPSender) []RTPHeaderExtensionParameter {
+	localDescription := pc.pendingLocalDescription
+	if localDescription == nil {
+		localDescription = pc.currentLocalDescription
+	}
+	if localDescription == nil || localDescription.parsed == nil {
+		return nil
+	}
+
+	localMedia := senderMediaDescription(localDescription.parsed, sender)
+	if localMedia == nil {
+		return nil
+	}
+	return pc.negotiatedHeaderExtensions(sender.track.Kind(), pc.getMidValue(localMedia))
+}
+
+// senderMediaDescription returns the media section of sd that announces the SSRC of the sender
+func senderMediaDescription(sd *sdp.SessionDescription, sender *RTPSender) *sdp.MediaDescription {
+	ssrc := strconv.FormatUint(uint64(sender.SSRC()), 10)
+	for _, media := range sd.MediaDescriptions {
+		for _, attr := range media.Attributes {
+			if attr.Key == sdp.AttrKeySSRC && strings.SplitN(attr.Value, " ", 2)[0] == ssrc {
+				return media
+			}
+		}
+	}
+	return nil
+}
+
+// drainSRTP accepts RTP/RTCP streams that don't match any SRTP stream of a receiver or sender.
+// They are passed to the OnUnhandledStream handler, without one their packets are discarded.
+// This is needed to make sure we don't block and provides useful debugging messages
 func (pc *PeerConnection) drainSRTP() {
 	go func() {
 		for {
@@ -1192,13 +1564,22 @@ func (pc *PeerConnection) drainSRTP() {
 				return
 			}
 
-			_, ssrc, err := srtpSession.AcceptStream()
+			stream, ssrc, err := srtpSession.AcceptStream()
 			if err != nil {
 				pc.log.Warnf("Failed to accept RTP %v \n", err)
 				return
 			}
 
 			pc.log.Debugf("Incoming unhandled RTP ssrc(%d)", ssrc)
+			for _, sender := range pc.GetSenders() {
+				if sender.SSRC() == ssrc {
+					pc.resolveSSRCCollision(sender)
+				}
+			}
+			if pc.dropClosedUnhandledStream(stream, ssrc, false) {
+				continue
+			}
+			pc.onUnhandledStream(&UnhandledStream{ssrc: ssrc, rtpReadStream: stream, pc: pc})
 		}
 	}()
 
@@ -1209,15 +1590,35 @@ func (pc *PeerConnection) drainSRTP() {
 			return
 		}
 
-		_, ssrc, err := srtcpSession.AcceptStream()
+		stream, ssrc, err := srtcpSession.AcceptStream()
 		if err != nil {
 			pc.log.Warnf("Failed to accept RTCP %v \n", err)
 			return
 		}
 		pc.log.Debugf("Incoming unhandled RTCP ssrc(%d)", ssrc)
+		if pc.dropClosedUnhandledStream(stream, ssrc, true) {
+			continue
+		}
+		pc.onUnhandledStream(&UnhandledStream{ssrc: ssrc, rtcp: true, rtcpReadStream: stream, pc: pc})
 	}
 }
 
+// dropClosedUnhandledStream closes a stream accepted again after its UnhandledStream was closed,
+// the packet that revealed it is dropped. It returns false for other streams
+func (pc *PeerConnection) dropClosedUnhandledStream(stream readStream, ssrc uint32, rtcp bool) bool {
+	pc.mu.RLock()
+	_, closed := pc.closedUnhandledSSRCs[unhandledSSRC{ssrc, rtcp}]
+	pc.mu.RUnlock()
+	if !closed {
+		return false
+	}
+
+	if err := stream.Close(); err != nil {
+		pc.log.Warnf("Failed to close stream of closed unhandled ssrc(%d): %s", ssrc, err)
+	}
+	return true
+}
+
 // RemoteDescription returns pendingRemoteDescription if it is not null and
 // otherwise it returns currentRemoteDescription. This property is used to
 // determine if setRemoteDescription has already been called.
@@ -1314,10 +1715,17 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 		}
 	}
 	if transceiver != nil {
+		if err := pc.checkSenderSSRC(track.SSRC(), transceiver.Sender); err != nil {
+			return nil, err
+		}
 		if err := transceiver.setSendingTrack(track); err != nil {
 			return nil, err
 		}
 	} else {
+		if err := pc.checkSenderSSRC(track.SSRC(), nil); err != nil {
+			return nil, err
+		}
+
 		receiver, err := pc.api.NewRTPReceiver(track.Kind(), pc.dtlsTransport)
 		if err != nil {
 			return nil, err
@@ -1365,7 +1773,7 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 			return nil, fmt.Errorf("no %s codecs found", kind.String())
 		}
 
-		track, err := pc.NewTrack(codecs[0].PayloadType, mathRand.Uint32(), util.RandSeq(trackDefaultIDLength), util.RandSeq(trackDefaultLabelLength))
+		track, err := pc.NewTrack(codecs[0].PayloadType, 0, util.RandSeq(trackDefaultIDLength), util.RandSeq(trackDefaultLabelLength))
 		if err != nil {
 			return nil, err
 		}
@@ -1408,6 +1816,14 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 		direction = init[0].Direction
 	}
 
+	ssrc := track.SSRC()
+	if len(init) == 1 && len(init[0].SendEncodings) != 0 && init[0].SendEncodings[0].SSRC != 0 {
+		ssrc = init[0].SendEncodings[0].SSRC
+	}
+	if err := pc.checkSenderSSRC(ssrc, nil); err != nil {
+		return nil, err
+	}
+
 	switch direction {
 	case RTPTransceiverDirectionSendrecv:
 		receiver, err := pc.api.NewRTPReceiver(track.Kind(), pc.dtlsTransport)
@@ -1419,6 +1835,7 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 		if err != nil {
 			return nil, err
 		}
+		applySendEncodings(sender, init)
 
 		return pc.newRTPTransceiver(
 			receiver,
@@ -1432,6 +1849,7 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 		if err != nil {
 			return nil, err
 		}
+		applySendEncodings(sender, init)
 
 		return pc.newRTPTransceiver(
 			nil,
@@ -1444,6 +1862,18 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 	}
 }
 
+// applySendEncodings configures the sender with the SSRC requested in RtpTransceiverInit.SendEncodings,
+// this allows the same Track to be sent with a different SSRC by every sender
+func applySendEncodings(sender *RTPSender, init []RtpTransceiverInit) {
+	if len(init) != 1 || len(init[0].SendEncodings) == 0 || init[0].SendEncodings[0].SSRC == 0 {
+		return
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	sender.ssrc = init[0].SendEncodings[0].SSRC
+}
+
 // CreateDataChannel creates a new DataChannel object with the given label
 // and optional DataChannelInit used to configure properties of the
 // underlying channel such as data reliability.
@@ -1622,6 +2052,21 @@ func (pc *PeerConnection) Close() error {
 			closeErrs = append(closeErrs, err)
 		}
 	}
+
+	pc.mu.Lock()
+	unhandledStreams, adoptedReceivers := pc.unhandledStreams, pc.adoptedReceivers
+	pc.unhandledStreams, pc.adoptedReceivers = map[*UnhandledStream]struct{}{}, map[*RTPReceiver]struct{}{}
+	pc.mu.Unlock()
+	for s := range unhandledStreams {
+		if err := s.Close(); err != nil {
+			closeErrs = append(closeErrs, err)
+		}
+	}
+	for r := range adoptedReceivers {
+		if err := r.Stop(); err != nil {
+			closeErrs = append(closeErrs, err)
+		}
+	}
 	return util.FlattenErrs(closeErrs)
 }
 
@@ -1633,7 +2078,17 @@ func (pc *PeerConnection) iceStateChange(newState ICEConnectionState) {
 	pc.onICEConnectionStateChange(newState)
 }
 
+// withoutDTLS reports if media is keyed by SDES or sent as plain RTP, there is no DTLS handshake then
+func (pc *PeerConnection) withoutDTLS() bool {
+	return pc.sdesCrypto != nil || pc.api.settingEngine.insecure.PlainRTP
+}
+
 func (pc *PeerConnection) addFingerprint(d *sdp.SessionDescription) error {
+	if pc.withoutDTLS() {
+		// There is no DTLS handshake to verify
+		return nil
+	}
+
 	// pion/webrtc#753
 	fingerprints, err := pc.configuration.Certificates[0].GetFingerprints()
 	if err != nil {
@@ -1645,34 +2100,54 @@ func (pc *PeerConnection) addFingerprint(d *sdp.SessionDescription) error {
 	return nil
 }
 
-func (pc *PeerConnection) addTransceiverSDP(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, transceivers ...*RTPTransceiver) error {
+func (pc *PeerConnection) addTransceiverSDP(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, codecs []*RTPCodec, transceivers ...*RTPTransceiver) error {
 	if len(transceivers) < 1 {
 		return fmt.Errorf("addTransceiverSDP() called with 0 transceivers")
 	}
 	// Use the first transceiver to generate the section attributes
 	t := transceivers[0]
-	media := sdp.NewJSEPMediaDescription(t.kind.String(), []string{}).
-		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()).
-		WithValueAttribute(sdp.AttrKeyMID, midValue).
+	protos := []string{"UDP", "TLS", "RTP", "SAVPF"}
+	media := sdp.NewJSEPMediaDescription(t.kind.String(), []string{})
+	switch {
+	case pc.api.settingEngine.insecure.PlainRTP:
+		// pion/sdp doesn't parse RTP/AVPF, the a=rtcp-fb lines are still announced
+		protos = []string{"RTP", "AVP"}
+		media.MediaName.Protos = protos
+	case pc.sdesCrypto != nil:
+		protos = pc.sdesProtos(midValue)
+		media.MediaName.Protos = protos
+	default:
+		media.WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String())
+	}
+	media.WithValueAttribute(sdp.AttrKeyMID, midValue).
 		WithICECredentials(iceParams.UsernameFragment, iceParams.Password).
 		WithPropertyAttribute(sdp.AttrKeyRTCPMux).
 		WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
+	if pc.sdesCrypto != nil {
+		media.WithValueAttribute("crypto", pc.sdesCrypto.attribute(pc.sdesTag(midValue)))
+	}
 
-	codecs := pc.api.mediaEngine.GetCodecsByKind(t.kind)
 	for _, codec := range codecs {
 		media.WithCodec(codec.PayloadType, codec.Name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
 
 		for _, feedback := range codec.RTPCodecCapability.RTCPFeedback {
-			media.WithValueAttribute("rtcp-fb", fmt.Sprintf("%d %s %s", codec.PayloadType, feedback.Type, feedback.Parameter))
+			value := fmt.Sprintf("%d %s", codec.PayloadType, feedback.Type)
+			if feedback.Parameter != "" {
+				value += " " + feedback.Parameter
+			}
+			media.WithValueAttribute("rtcp-fb", value)
 		}
 	}
+	for _, extension := range pc.localHeaderExtensions(t.kind, midValue) {
+		media.WithValueAttribute("extmap", fmt.Sprintf("%d %s", extension.ID, extension.URI))
+	}
 	if len(codecs) == 0 {
 		// Explicitly reject track if we don't have the codec
 		d.WithMedia(&sdp.MediaDescription{
 			MediaName: sdp.MediaName{
 				Media:   t.kind.String(),
 				Port:    sdp.RangedPort{Value: 0},
-				Protos:  []string{"UDP", "TLS", "RTP", "SAVPF"},
+				Protos:  protos,
 				Formats: []string{"0"},
 			},
 		})
@@ -1682,9 +2157,11 @@ func (pc *PeerConnection) addTransceiverSDP(d *sdp.SessionDescription, midValue
 	for _, mt := range transceivers {
 		if mt.Sender != nil && mt.Sender.track != nil {
 			track := mt.Sender.track
-			media = media.WithMediaSource(track.SSRC(), track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+			media = media.WithMediaSource(mt.Sender.SSRC(), pc.cname, track.StreamID(), track.ID())
 			if pc.configuration.SDPSemantics == SDPSemanticsUnifiedPlan {
-				media = media.WithPropertyAttribute("msid:" + track.Label() + " " + track.ID())
+				for _, streamID := range track.StreamIDs() {
+					media = media.WithPropertyAttribute("msid:" + streamID + " " + track.ID())
+				}
 				break
 			}
 		}
@@ -1698,6 +2175,41 @@ func (pc *PeerConnection) addTransceiverSDP(d *sdp.SessionDescription, midValue
 	return nil
 }
 
+// sdesTag returns the tag of the local a=crypto line. An answer uses the tag of the line it accepted, RFC 4568 Section 5.1.2
+func (pc *PeerConnection) sdesTag(mid string) int {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return sdesOfferTag
+	}
+
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if pc.getMidValue(media) != mid {
+			continue
+		}
+		if remoteCrypto, err := sdesCryptoFromMediaDescription(media); err == nil {
+			return remoteCrypto.tag
+		}
+	}
+	return sdesOfferTag
+}
+
+// sdesProtos returns the transport profile of a media section with SDES keying. An answer uses
+// the profile of the offered media section, RFC 3264 Section 6
+func (pc *PeerConnection) sdesProtos(mid string) []string {
+	if remoteDescription := pc.RemoteDescription(); remoteDescription != nil && remoteDescription.parsed != nil {
+		for _, media := range remoteDescription.parsed.MediaDescriptions {
+			if pc.getMidValue(media) == mid && isSecureRTPProfile(media.MediaName.Protos) {
+				return media.MediaName.Protos
+			}
+		}
+	}
+
+	if profile := pc.api.settingEngine.sdes.Profile; profile != "" {
+		return strings.Split(profile, "/")
+	}
+	return []string{"RTP", "SAVPF"}
+}
+
 func (pc *PeerConnection) addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole) {
 	media := (&sdp.MediaDescription{
 		MediaName: sdp.MediaName{
@@ -1724,8 +2236,8 @@ func (pc *PeerConnection) addDataMediaSection(d *sdp.SessionDescription, midValu
 	d.WithMedia(media)
 }
 
-// NewTrack Creates a new Track
-func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
+// NewTrack Creates a new Track, see NewTrack for the meaning of ssrc and streamIDs
+func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label string, streamIDs ...string) (*Track, error) {
 	codec, err := pc.api.mediaEngine.getCodec(payloadType)
 	if err != nil {
 		return nil, err
@@ -1733,7 +2245,91 @@ func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label str
 		return nil, fmt.Errorf("codec payloader not set")
 	}
 
-	return NewTrack(payloadType, ssrc, id, label, codec)
+	if ssrc == 0 {
+		ssrc = randomSSRC(pc.senderSSRCs(nil))
+	}
+	return NewTrack(payloadType, ssrc, id, label, codec, streamIDs...)
+}
+
+// generateCNAME returns a random CNAME with 96 bits of entropy, RFC 7022 Section 4.2
+func generateCNAME() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// senderSSRCs returns the SSRCs of every RTPSender except skip
+func (pc *PeerConnection) senderSSRCs(skip *RTPSender) map[uint32]struct{} {
+	ssrcs := map[uint32]struct{}{}
+	for _, sender := range pc.GetSenders() {
+		if sender != skip {
+			ssrcs[sender.SSRC()] = struct{}{}
+		}
+	}
+	return ssrcs
+}
+
+// checkSenderSSRC returns ErrSSRCInUse if another RTPSender of the PeerConnection already sends with ssrc
+func (pc *PeerConnection) checkSenderSSRC(ssrc uint32, skip *RTPSender) error {
+	if _, ok := pc.senderSSRCs(skip)[ssrc]; ok {
+		return ErrSSRCInUse
+	}
+	return nil
+}
+
+// resolveSSRCCollision moves sender to an unused SSRC after the remote was found to use
+// the same one, RFC 3550 Section 8.2. If the sender was started a BYE is sent for the old SSRC.
+// SSRCs announced in the local description are kept, see OnSSRCCollision
+func (pc *PeerConnection) resolveSSRCCollision(sender *RTPSender) {
+	ssrc := sender.SSRC()
+	if local := pc.LocalDescription(); local != nil && local.parsed != nil {
+		if _, ok := ssrcsFromSDP(local.parsed)[ssrc]; ok {
+			pc.onSSRCCollision(sender, ssrc, ssrc)
+			return
+		}
+	}
+
+	inUse := pc.senderSSRCs(nil)
+	if remote := pc.RemoteDescription(); remote != nil {
+		for ssrc := range ssrcsFromSDP(remote.parsed) {
+			inUse[ssrc] = struct{}{}
+		}
+	}
+
+	newSSRC := randomSSRC(inUse)
+	hasSent := sender.hasSent()
+	oldSSRC, err := sender.changeSSRC(newSSRC)
+	if err != nil {
+		pc.log.Warnf("Failed to change SSRC %d to %d: %s", oldSSRC, newSSRC, err)
+	}
+
+	if hasSent {
+		if err := pc.WriteRTCP([]rtcp.Packet{&rtcp.Goodbye{Sources: []uint32{oldSSRC}}}); err != nil {
+			pc.log.Warnf("Failed to send BYE for SSRC %d: %s", oldSSRC, err)
+		}
+	}
+
+	pc.onSSRCCollision(sender, oldSSRC, newSSRC)
+}
+
+// ssrcsFromSDP returns the SSRCs announced with a=ssrc in any media section
+func ssrcsFromSDP(desc *sdp.SessionDescription) map[uint32]struct{} {
+	ssrcs := map[uint32]struct{}{}
+	for _, media := range desc.MediaDescriptions {
+		for _, attr := range media.Attributes {
+			if attr.Key != sdp.AttrKeySSRC {
+				continue
+			}
+
+			ssrc, err := strconv.ParseUint(strings.Split(attr.Value, " ")[0], 10, 32)
+			if err == nil {
+				ssrcs[uint32(ssrc)] = struct{}{}
+			}
+		}
+	}
+	return ssrcs
 }
 
 func (pc *PeerConnection) newRTPTransceiver(
@@ -1858,6 +2454,13 @@ func (pc *PeerConnection) GetStats() StatsReport {
 	}
 
 	pc.iceGatherer.collectStats(statsCollector)
+	pc.dtlsTransport.collectStats(statsCollector)
+
+	for _, t := range pc.rtpTransceivers {
+		if t.Receiver != nil {
+			t.Receiver.collectStats(statsCollector)
+		}
+	}
 
 	stats := PeerConnectionStats{
 		Timestamp:             statsTimestampNow(),
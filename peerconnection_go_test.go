@@ -7,10 +7,12 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"math/big"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -342,6 +344,169 @@ func TestPeerConnection_ShutdownNoDTLS(t *testing.T) {
 	}
 }
 
+func TestPeerConnection_DTLSVerifyPeerCertificate(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	identityCertificate := func(commonName string) Certificate {
+		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.Nil(t, err)
+
+		cert, err := NewCertificate(sk, x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: commonName},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().AddDate(0, 1, 0),
+		})
+		assert.Nil(t, err)
+		return *cert
+	}
+
+	// connect returns the final state of the offerer's DTLSTransport and why it failed
+	connect := func(s SettingEngine, offerConfig, answerConfig Configuration) (DTLSTransportState, error) {
+		offerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(offerConfig)
+		assert.Nil(t, err)
+		answerPC, err := NewPeerConnection(answerConfig)
+		assert.Nil(t, err)
+
+		dtlsDone := make(chan DTLSTransportState, 1)
+		dtlsErr := make(chan error, 1)
+		offerPC.dtlsTransport.OnStateChange(func(state DTLSTransportState) {
+			if state == DTLSTransportStateConnected || state == DTLSTransportStateFailed {
+				dtlsErr <- offerPC.dtlsTransport.Err()
+				dtlsDone <- state
+			}
+		})
+
+		assert.Nil(t, signalPair(offerPC, answerPC))
+		state, err := <-dtlsDone, <-dtlsErr
+
+		assert.Nil(t, offerPC.Close())
+		assert.Nil(t, answerPC.Close())
+		return state, err
+	}
+
+	t.Run("Callback", func(t *testing.T) {
+		answerCert := identityCertificate("answer")
+		answerFingerprints, err := answerCert.GetFingerprints()
+		assert.Nil(t, err)
+
+		var rawCerts [][]byte
+		var fingerprint DTLSFingerprint
+		s := SettingEngine{}
+		s.SetDTLSVerifyPeerCertificate(func(r [][]byte, fp DTLSFingerprint) error {
+			rawCerts, fingerprint = r, fp
+			return nil
+		})
+		state, err := connect(s, Configuration{}, Configuration{Certificates: []Certificate{answerCert}})
+		assert.Equal(t, DTLSTransportStateConnected, state)
+		assert.Nil(t, err)
+		assert.Equal(t, [][]byte{answerCert.x509Cert.Raw}, rawCerts)
+		assert.Equal(t, answerFingerprints[0].Algorithm, fingerprint.Algorithm)
+		assert.True(t, strings.EqualFold(answerFingerprints[0].Value, fingerprint.Value))
+
+		notPinned := fmt.Errorf("certificate is not pinned")
+		s.SetDTLSVerifyPeerCertificate(func([][]byte, DTLSFingerprint) error {
+			return notPinned
+		})
+		state, err = connect(s, Configuration{}, Configuration{})
+		assert.Equal(t, DTLSTransportStateFailed, state)
+		assert.Equal(t, notPinned, err)
+	})
+
+	t.Run("PeerIdentity", func(t *testing.T) {
+		answerConfig := Configuration{Certificates: []Certificate{identityCertificate("alice@example.com")}}
+
+		state, err := connect(SettingEngine{}, Configuration{PeerIdentity: "alice@example.com"}, answerConfig)
+		assert.Equal(t, DTLSTransportStateConnected, state)
+		assert.Nil(t, err)
+
+		state, err = connect(SettingEngine{}, Configuration{PeerIdentity: "bob@example.com"}, answerConfig)
+		assert.Equal(t, DTLSTransportStateFailed, state)
+		assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrPeerIdentityMismatch}, err)
+	})
+}
+
+func TestPeerConnection_AnsweringDTLSRole(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	t.Run("Server", func(t *testing.T) {
+		s := SettingEngine{}
+		assert.Nil(t, s.SetAnsweringDTLSRole(DTLSRoleServer))
+
+		offerPC, err := NewPeerConnection(Configuration{})
+		assert.Nil(t, err)
+		answerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.Nil(t, err)
+
+		connected := make(chan struct{}, 2)
+		for _, pc := range []*PeerConnection{offerPC, answerPC} {
+			pc.dtlsTransport.OnStateChange(func(state DTLSTransportState) {
+				if state == DTLSTransportStateConnected {
+					connected <- struct{}{}
+				}
+			})
+		}
+
+		assert.Nil(t, signalPair(offerPC, answerPC))
+		assert.Contains(t, answerPC.LocalDescription().SDP, "a=setup:passive")
+		<-connected
+		<-connected
+
+		assert.Equal(t, DTLSRoleClient, offerPC.dtlsTransport.Role())
+		assert.Equal(t, DTLSRoleServer, answerPC.dtlsTransport.Role())
+
+		assert.Nil(t, offerPC.Close())
+		assert.Nil(t, answerPC.Close())
+	})
+
+	t.Run("Remote offer decides", func(t *testing.T) {
+		s := SettingEngine{}
+		assert.Nil(t, s.SetAnsweringDTLSRole(DTLSRoleServer))
+
+		offerPC, err := NewPeerConnection(Configuration{})
+		assert.Nil(t, err)
+		answerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.Nil(t, err)
+
+		_, err = offerPC.CreateDataChannel("data", nil)
+		assert.Nil(t, err)
+		offer, err := offerPC.CreateOffer(nil)
+		assert.Nil(t, err)
+
+		offer.SDP = strings.Replace(offer.SDP, "a=setup:actpass", "a=setup:passive", -1)
+		assert.Nil(t, answerPC.SetRemoteDescription(offer))
+		answer, err := answerPC.CreateAnswer(nil)
+		assert.Nil(t, err)
+		assert.Contains(t, answer.SDP, "a=setup:active")
+
+		assert.Nil(t, offerPC.Close())
+		assert.Nil(t, answerPC.Close())
+	})
+
+	t.Run("Answer with actpass", func(t *testing.T) {
+		offerPC, answerPC, err := newPair()
+		assert.Nil(t, err)
+
+		_, err = offerPC.CreateDataChannel("data", nil)
+		assert.Nil(t, err)
+		offer, err := offerPC.CreateOffer(nil)
+		assert.Nil(t, err)
+		assert.Nil(t, offerPC.SetLocalDescription(offer))
+		assert.Nil(t, answerPC.SetRemoteDescription(offer))
+		answer, err := answerPC.CreateAnswer(nil)
+		assert.Nil(t, err)
+
+		answer.SDP = strings.Replace(answer.SDP, "a=setup:active", "a=setup:actpass", -1)
+		err = offerPC.SetRemoteDescription(answer)
+		assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrAnswerSetupActpass}, err)
+
+		assert.Nil(t, offerPC.Close())
+		assert.Nil(t, answerPC.Close())
+	})
+}
+
 func TestPeerConnection_PeropertyGetters(t *testing.T) {
 	pc := &PeerConnection{
 		currentLocalDescription:  &SessionDescription{},
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"reflect"
 	"strings"
@@ -18,6 +19,7 @@ import (
 	"github.com/pion/sdp/v2"
 	"github.com/pion/transport/test"
 	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,6 +33,79 @@ func offerMediaHasDirection(offer SessionDescription, kind RTPCodecType, directi
 	return false
 }
 
+// mediaTestPair holds the PeerConnections of a media test, see newMediaTestPair
+type mediaTestPair struct {
+	t             *testing.T
+	offer, answer *PeerConnection
+
+	settingEngine  SettingEngine
+	registerCodecs func(m *MediaEngine, offerer bool)
+
+	// dataChannelOpened is closed when the data channel created by signalPair is open
+	dataChannelOpened chan struct{}
+}
+
+// newMediaTestPair creates an offerer and an answerer with the same SettingEngine. The candidate
+// selection timeout is shortened so no routine outlives the test, configure changes the SettingEngine
+// further. registerCodecs sets up the MediaEngine of each side, nil registers the default codecs
+func newMediaTestPair(t *testing.T, configure func(s *SettingEngine), registerCodecs func(m *MediaEngine, offerer bool)) *mediaTestPair {
+	p := &mediaTestPair{
+		t:                 t,
+		registerCodecs:    registerCodecs,
+		dataChannelOpened: make(chan struct{}),
+	}
+	p.settingEngine.SetCandidateSelectionTimeout(time.Second)
+	if configure != nil {
+		configure(&p.settingEngine)
+	}
+
+	p.offer, p.answer = p.newPeerConnection(true), p.newPeerConnection(false)
+	p.answer.OnDataChannel(func(d *DataChannel) {
+		d.OnOpen(func() {
+			close(p.dataChannelOpened)
+		})
+	})
+	return p
+}
+
+// newPeerConnection creates a PeerConnection configured like the offerer or the answerer of the pair
+func (p *mediaTestPair) newPeerConnection(offerer bool) *PeerConnection {
+	m := MediaEngine{}
+	if p.registerCodecs == nil {
+		m.RegisterDefaultCodecs()
+	} else {
+		p.registerCodecs(&m, offerer)
+	}
+
+	pc, err := NewAPI(WithMediaEngine(m), WithSettingEngine(p.settingEngine)).NewPeerConnection(Configuration{})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return pc
+}
+
+// registerVP8 registers VP8 as the only codec of either side
+func registerVP8(m *MediaEngine, _ bool) {
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+}
+
+// signal exchanges offer and answer, see signalPair
+func (p *mediaTestPair) signal() {
+	if err := signalPair(p.offer, p.answer); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// close closes both PeerConnections. When DTLS is used signalPair negotiated a data channel,
+// don't tear down while SCTP is still being started then
+func (p *mediaTestPair) close() {
+	if !p.offer.withoutDTLS() {
+		<-p.dataChannelOpened
+	}
+	assert.NoError(p.t, p.offer.Close())
+	assert.NoError(p.t, p.answer.Close())
+}
+
 func TestSRTPDrainLeak(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
@@ -132,6 +207,137 @@ func TestSRTPDrainLeak(t *testing.T) {
 	}
 }
 
+func TestPeerConnection_OnUnhandledStream(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, nil, nil)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	rtpStreams, rtcpStreams := make(chan *UnhandledStream, 1), make(chan *UnhandledStream, 1)
+	pcAnswer.OnUnhandledStream(func(s *UnhandledStream) {
+		if s.IsRTCP() {
+			rtcpStreams <- s
+		} else {
+			rtpStreams <- s
+		}
+	})
+
+	dtlsConnected := make(chan interface{})
+	pcOffer.dtlsTransport.OnStateChange(func(s DTLSTransportState) {
+		if s == DTLSTransportStateConnected {
+			close(dtlsConnected)
+		}
+	})
+
+	pair.signal()
+	<-dtlsConnected
+
+	srtpSession, err := pcOffer.dtlsTransport.getSRTPSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srtpStream, err := srtpSession.OpenWriteStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srtcpSession, err := pcOffer.dtlsTransport.getSRTCPSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srtcpStream, err := srtcpSession.OpenWriteStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := uint16(0); i < 2; i++ {
+		if _, err = srtpStream.WriteRTP(&rtp.Header{Version: 2, SSRC: 5000, PayloadType: DefaultPayloadTypeVP8, SequenceNumber: i}, []byte{0x10, 0xAA}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rawPLI, err := rtcp.Marshal([]rtcp.Packet{&rtcp.PictureLossIndication{SenderSSRC: 5001, MediaSSRC: 5001}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srtcpStream.Write(rawPLI); err != nil {
+		t.Fatal(err)
+	}
+
+	rtcpUnhandled := <-rtcpStreams
+	assert.Equal(t, uint32(5001), rtcpUnhandled.SSRC())
+	assert.Equal(t, rawPLI, rtcpUnhandled.FirstPacket())
+	_, _, err = rtcpUnhandled.NewTrack(nil)
+	assert.Error(t, err)
+
+	rtpUnhandled := <-rtpStreams
+	assert.Equal(t, uint32(5000), rtpUnhandled.SSRC())
+
+	// The first packet is still returned by the adopted Track
+	track, _, err := rtpUnhandled.NewTrack(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, VP8, track.Codec().Name)
+	assert.Equal(t, uint32(5000), track.SSRC())
+
+	for i := uint16(0); i < 2; i++ {
+		p, readErr := track.ReadRTP()
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+		assert.Equal(t, i, p.SequenceNumber)
+		assert.Equal(t, []byte{0x10, 0xAA}, p.Payload)
+	}
+
+	_, err = rtpUnhandled.Read(make([]byte, receiveMTU))
+	assert.Error(t, err)
+
+	// Packets of a closed stream are dropped instead of being reported again
+	assert.NoError(t, rtcpUnhandled.Close())
+	rawPLI2, err := rtcp.Marshal([]rtcp.Packet{&rtcp.PictureLossIndication{SenderSSRC: 5002, MediaSSRC: 5002}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range [][]byte{rawPLI, rawPLI2} {
+		if _, err = srtcpStream.Write(raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, uint32(5002), (<-rtcpStreams).SSRC())
+
+	// Only the open stream is kept, the adopted one is tracked by its receiver
+	pcAnswer.mu.RLock()
+	assert.Len(t, pcAnswer.unhandledStreams, 1)
+	assert.Len(t, pcAnswer.adoptedReceivers, 1)
+	pcAnswer.mu.RUnlock()
+
+	pair.close()
+
+	_, err = track.ReadRTP()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPeerConnection_ClosedUnhandledSSRCsLimit(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	for ssrc := uint32(1); ssrc <= maxClosedUnhandledSSRCs+1; ssrc++ {
+		s := &UnhandledStream{ssrc: ssrc, rtpReadStream: &packetStream{}, pc: pc}
+		assert.NoError(t, s.Close())
+	}
+
+	// The oldest SSRC is forgotten, its packets are reported again
+	assert.Len(t, pc.closedUnhandledSSRCs, maxClosedUnhandledSSRCs)
+	assert.False(t, pc.dropClosedUnhandledStream(&packetStream{}, 1, false))
+	assert.True(t, pc.dropClosedUnhandledStream(&packetStream{}, 2, false))
+	assert.True(t, pc.dropClosedUnhandledStream(&packetStream{}, maxClosedUnhandledSSRCs+1, false))
+
+	assert.NoError(t, pc.Close())
+}
+
 /*
 Integration test for bi-directional peers
 
@@ -565,7 +771,11 @@ func TestPeerConnection_Media_Closed(t *testing.T) {
 		time.Sleep(time.Second)
 		closeChan <- pcAnswer.Close()
 	}()
-	if _, err = vp8Reader.Read(make([]byte, 1)); err != io.EOF {
+	// The packets that arrived before OnTrack fired are still buffered, read them first
+	for err == nil {
+		_, err = vp8Reader.Read(make([]byte, receiveMTU))
+	}
+	if err != io.EOF {
 		t.Fatal("Reading from closed Track did not return io.EOF")
 	} else if err = <-closeChan; err != nil {
 		t.Fatal(err)
@@ -592,6 +802,809 @@ func TestPeerConnection_Media_Closed(t *testing.T) {
 	}
 }
 
+// When the m-line leaves only one codec OnTrack fires before any media arrives,
+// and the first packet that is sent is the first one that is read
+func TestPeerConnection_Media_CodecFromSDP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, nil, registerVP8)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	if _, err := pcAnswer.AddTransceiver(RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onTrack := make(chan *Track)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		onTrack <- track
+	})
+
+	pair.signal()
+
+	track := <-onTrack
+	assert.Equal(t, VP8, track.Codec().Name)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), track.PayloadType())
+
+	<-sender.sendCalled
+	if err = vp8Track.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := track.ReadRTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []byte{0x10, 0xAA}, p.Payload)
+
+	pair.close()
+}
+
+// A Track is sent with the payload type the remote negotiated and the SSRC of the RTPSender
+func TestPeerConnection_Media_SenderRewritesHeader(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const (
+		answerPayloadType = 100
+		senderSSRC        = 5000
+	)
+
+	pair := newMediaTestPair(t, nil, func(m *MediaEngine, offerer bool) {
+		if offerer {
+			m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+		} else {
+			m.RegisterCodec(NewRTPVP8Codec(answerPayloadType, 90000))
+		}
+	})
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transceiver, err := pcOffer.AddTransceiverFromTrack(vp8Track, RtpTransceiverInit{
+		Direction:     RTPTransceiverDirectionSendrecv,
+		SendEncodings: []RTPEncodingParameters{{RTPCodingParameters{SSRC: senderSSRC}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	onTrack := make(chan *Track)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		onTrack <- track
+	})
+
+	pair.signal()
+
+	<-transceiver.Sender.sendCalled
+	assert.Equal(t, uint32(senderSSRC), transceiver.Sender.SSRC())
+	assert.Equal(t, uint8(answerPayloadType), transceiver.Sender.PayloadType())
+
+	// Packets written with another SSRC are moved into the sequence number space of the sender
+	for _, sequenceNumber := range []uint16{65535, 0} {
+		if err = vp8Track.WriteRTP(&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    DefaultPayloadTypeVP8,
+				SequenceNumber: sequenceNumber,
+				SSRC:           1234,
+			},
+			Payload: []byte{0x10, 0xAA},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	track := <-onTrack
+	assert.Equal(t, uint32(senderSSRC), track.SSRC())
+	assert.Equal(t, uint8(answerPayloadType), track.PayloadType())
+
+	first, err := track.ReadRTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := track.ReadRTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []*rtp.Packet{first, second} {
+		assert.Equal(t, uint32(senderSSRC), p.SSRC)
+		assert.Equal(t, uint8(answerPayloadType), p.PayloadType)
+	}
+	assert.Equal(t, first.SequenceNumber+1, second.SequenceNumber)
+
+	pair.close()
+}
+
+func TestPeerConnection_Media_TrackLifecycle(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, func(s *SettingEngine) {
+		s.SetTrackInactivityTimeout(250 * time.Millisecond)
+	}, registerVP8)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	if _, err := pcAnswer.AddTransceiver(RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	muted, unmuted, ended := make(chan struct{}, 1), make(chan struct{}, 1), make(chan struct{})
+	onTrack := make(chan *Track)
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		track.OnMute(func() {
+			muted <- struct{}{}
+		})
+		track.OnUnmute(func() {
+			unmuted <- struct{}{}
+		})
+		track.OnEnded(func() {
+			close(ended)
+		})
+		onTrack <- track
+	})
+
+	pair.signal()
+
+	track := <-onTrack
+	assert.False(t, track.Muted())
+
+	// Nothing was sent yet
+	<-muted
+	assert.True(t, track.Muted())
+
+	<-sender.sendCalled
+	if err = vp8Track.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// Packets unmute and BYE ends the track without being read
+	<-unmuted
+	assert.False(t, track.Muted())
+
+	if err = pcOffer.WriteRTCP([]rtcp.Packet{&rtcp.Goodbye{Sources: []uint32{vp8Track.SSRC()}}}); err != nil {
+		t.Fatal(err)
+	}
+	<-ended
+	assert.True(t, track.Ended())
+
+	pair.close()
+}
+
+func TestPeerConnection_Media_SSRCCollision(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, nil, registerVP8)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	offerTrack, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 0, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, uint32(0), offerTrack.SSRC())
+	if _, err = pcOffer.AddTrack(offerTrack); err != nil {
+		t.Fatal(err)
+	}
+
+	// SSRCs must be unique across the senders of a PeerConnection
+	duplicateTrack, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, offerTrack.SSRC(), "video2", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pcOffer.AddTrack(duplicateTrack)
+	assert.Equal(t, ErrSSRCInUse, err)
+	_, err = pcOffer.AddTransceiverFromTrack(duplicateTrack)
+	assert.Equal(t, ErrSSRCInUse, err)
+
+	// The answerer picked the same SSRC as the offerer
+	answerTrack, err := pcAnswer.NewTrack(DefaultPayloadTypeVP8, offerTrack.SSRC(), "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerSender, err := pcAnswer.AddTrack(answerTrack)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collision := make(chan [2]uint32, 1)
+	pcAnswer.OnSSRCCollision(func(sender *RTPSender, oldSSRC, newSSRC uint32) {
+		assert.Equal(t, answerSender, sender)
+		collision <- [2]uint32{oldSSRC, newSSRC}
+	})
+
+	onTrack := make(chan *Track)
+	pcOffer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		onTrack <- track
+	})
+
+	pair.signal()
+
+	ssrcs := <-collision
+	newSSRC := ssrcs[1]
+	assert.Equal(t, offerTrack.SSRC(), ssrcs[0])
+	assert.NotEqual(t, offerTrack.SSRC(), newSSRC)
+	assert.Equal(t, newSSRC, answerSender.SSRC())
+	assert.NotContains(t, pcAnswer.LocalDescription().SDP, fmt.Sprintf("a=ssrc:%d ", offerTrack.SSRC()))
+
+	<-answerSender.sendCalled
+	go func() {
+		for {
+			if routineErr := answerTrack.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 1}); routineErr != nil {
+				return
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+	}()
+
+	track := <-onTrack
+	assert.Equal(t, newSSRC, track.SSRC())
+
+	// The remote now sends with the SSRC announced in the answer
+	srtpSession, err := pcOffer.dtlsTransport.getSRTPSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeStream, err := srtpSession.OpenWriteStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = writeStream.WriteRTP(&rtp.Header{Version: 2, SSRC: newSSRC, PayloadType: DefaultPayloadTypeVP8}, []byte{0xAA}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The answer announced the SSRC, it is kept so the remote keeps receiving
+	assert.Equal(t, [2]uint32{newSSRC, newSSRC}, <-collision)
+	assert.Equal(t, newSSRC, answerSender.SSRC())
+
+	p, err := track.ReadRTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, newSSRC, p.SSRC)
+
+	pair.close()
+}
+
+func TestPeerConnection_Media_StreamID(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, nil, nil)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	// Both tracks belong to the same MediaStream while keeping their own labels
+	tracks := map[string]*Track{}
+	for _, payloadType := range []uint8{DefaultPayloadTypeOpus, DefaultPayloadTypeVP8} {
+		track, trackErr := pcOffer.NewTrack(payloadType, 0, fmt.Sprintf("track-%d", payloadType), fmt.Sprintf("label-%d", payloadType), "stream")
+		if trackErr != nil {
+			t.Fatal(trackErr)
+		}
+		assert.Equal(t, "stream", track.StreamID())
+		if _, trackErr = pcOffer.AddTrack(track); trackErr != nil {
+			t.Fatal(trackErr)
+		}
+		if _, trackErr = pcAnswer.AddTransceiver(track.Kind(), RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly}); trackErr != nil {
+			t.Fatal(trackErr)
+		}
+		tracks[track.ID()] = track
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(tracks))
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		assert.Equal(t, "stream", track.StreamID())
+		assert.Contains(t, tracks, track.ID())
+		wg.Done()
+	})
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A single random CNAME is announced for all tracks instead of their labels
+	for _, media := range offer.parsed.MediaDescriptions {
+		for _, attr := range media.Attributes {
+			if attr.Key == sdp.AttrKeySSRC && strings.Contains(attr.Value, "cname:") {
+				assert.True(t, strings.HasSuffix(attr.Value, " cname:"+pcOffer.cname))
+			}
+		}
+	}
+	assert.NotEqual(t, pcOffer.cname, pcAnswer.cname)
+
+	pair.signal()
+
+	go func() {
+		for {
+			for _, track := range tracks {
+				if routineErr := track.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 1}); routineErr != nil {
+					return
+				}
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+	}()
+	wg.Wait()
+
+	pair.close()
+}
+
+func TestPeerConnection_Media_DTMF(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, nil, nil)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	if _, err := pcAnswer.AddTransceiver(RTPCodecTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+
+	videoTrack, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 0, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	videoSender, err := pcOffer.AddTrack(videoTrack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, videoSender.DTMF())
+
+	audioTrack, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, 0, "audio", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(audioTrack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, sender.DTMF().CanInsertDTMF())
+
+	type dtmfEvent struct {
+		tone     string
+		duration time.Duration
+	}
+	received := make(chan dtmfEvent, 2)
+	onTrack := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		if track.Kind() != RTPCodecTypeAudio {
+			return
+		}
+
+		track.OnDTMF(func(tone string, duration time.Duration) {
+			received <- dtmfEvent{tone, duration}
+		})
+		close(onTrack)
+
+		for {
+			p, readErr := track.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			assert.Equal(t, uint8(DefaultPayloadTypeOpus), track.PayloadType())
+			assert.Equal(t, sender.SSRC(), p.SSRC)
+		}
+	})
+
+	pair.signal()
+
+	<-sender.sendCalled
+	go func() {
+		for {
+			if routineErr := audioTrack.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 960}); routineErr != nil {
+				return
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+	}()
+	<-onTrack
+
+	dtmf := sender.DTMF()
+	assert.True(t, dtmf.CanInsertDTMF())
+
+	toneChanges := make(chan string, 3)
+	dtmf.OnToneChange(func(tone string) {
+		toneChanges <- tone
+	})
+
+	_, isInvalidCharacter := dtmf.InsertDTMF("1x", 0, 0).(*rtcerr.InvalidCharacterError)
+	assert.True(t, isInvalidCharacter)
+
+	assert.NoError(t, dtmf.InsertDTMF("1#", 120*time.Millisecond, time.Millisecond))
+	for _, expected := range []dtmfEvent{{"1", 120 * time.Millisecond}, {"#", 120 * time.Millisecond}} {
+		assert.Equal(t, expected, <-received)
+	}
+	for _, expected := range []string{"1", "#", ""} {
+		assert.Equal(t, expected, <-toneChanges)
+	}
+	assert.Equal(t, "", dtmf.ToneBuffer())
+
+	pair.close()
+	assert.False(t, dtmf.CanInsertDTMF())
+}
+
+func TestPeerConnection_Media_AudioLevel(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, nil, nil)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	if _, err := pcAnswer.AddTransceiver(RTPCodecTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+
+	audioTrack, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, 0, "audio", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(audioTrack); err != nil {
+		t.Fatal(err)
+	}
+
+	levelReceived := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		received := false
+		for {
+			if _, readErr := track.ReadRTP(); readErr != nil {
+				return
+			}
+
+			audioLevel, ok := track.AudioLevel()
+			if received || !ok {
+				continue
+			}
+			assert.Equal(t, media.AudioLevel{Level: 30, Voice: true}, audioLevel)
+
+			sources := receiver.GetSynchronizationSources()
+			if assert.Len(t, sources, 1) && assert.NotNil(t, sources[0].AudioLevel) {
+				assert.InDelta(t, math.Pow(10, -1.5), *sources[0].AudioLevel, 1e-9)
+			}
+			received = true
+			close(levelReceived)
+		}
+	})
+
+	pair.signal()
+
+	go func() {
+		for {
+			sample := media.Sample{Data: []byte{0xAA}, Samples: 960, AudioLevel: &media.AudioLevel{Level: 30, Voice: true}}
+			if routineErr := audioTrack.WriteSample(sample); routineErr != nil {
+				return
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+	}()
+	<-levelReceived
+
+	pair.close()
+}
+
+func TestPeerConnection_Media_EncodedFrameTransform(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, nil, nil)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	if _, err := pcAnswer.AddTransceiver(RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 0, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The transforms prefix frames with a marker and the index of the frame, which is
+	// larger than a packet so the receiver has to reassemble the frame
+	prefix := bytes.Repeat([]byte{0xE2}, 2000)
+	sender.SetTransform(func(frame *EncodedFrame) error {
+		assert.Equal(t, sender.SSRC(), frame.SSRC)
+		assert.Equal(t, frame.Data[0] == 0, frame.Keyframe)
+		frame.Data = append(append([]byte{}, prefix...), frame.Data...)
+		return nil
+	})
+
+	samplesRead := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		receiver.SetTransform(func(frame *EncodedFrame) error {
+			assert.Equal(t, track.SSRC(), frame.SSRC)
+			if !bytes.HasPrefix(frame.Data, prefix) {
+				return fmt.Errorf("frame wasn't transformed by the sender")
+			}
+			frame.Data = frame.Data[len(prefix):]
+			return nil
+		})
+
+		last := -1
+		for {
+			sample, readErr := track.ReadSample()
+			if readErr != nil {
+				return
+			}
+
+			// Frames are [P bit, index], every tenth frame is a keyframe
+			if !assert.Len(t, sample.Data, 2) {
+				continue
+			}
+			index := int(sample.Data[1])
+			assert.Equal(t, index%10 != 0, sample.Data[0] == 1)
+			if last != -1 {
+				assert.Equal(t, (last+1)%256, index)
+			}
+			last = index
+			if index == 20 {
+				close(samplesRead)
+			}
+		}
+	})
+
+	pair.signal()
+
+	go func() {
+		for i := 0; ; i++ {
+			data := []byte{1, byte(i)}
+			if i%10 == 0 {
+				data[0] = 0
+			}
+			if routineErr := vp8Track.WriteSample(media.Sample{Data: data, Samples: 3000}); routineErr != nil {
+				return
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+	}()
+	<-samplesRead
+
+	pair.close()
+}
+
+func TestPeerConnection_Media_SDES(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, func(s *SettingEngine) {
+		s.EnableSDESKeying()
+		assert.NoError(t, s.SetSDESProfile("RTP/SAVP"))
+	}, nil)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	// An offer with DTLS-SRTP is rejected
+	dtlsOfferer, err := NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = dtlsOfferer.AddTransceiver(RTPCodecTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+	dtlsOffer, err := dtlsOfferer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdesPeer := pair.newPeerConnection(false)
+	assert.Error(t, sdesPeer.SetRemoteDescription(dtlsOffer))
+	assert.NoError(t, sdesPeer.Close())
+	assert.NoError(t, dtlsOfferer.Close())
+
+	if _, err = pcAnswer.AddTransceiver(RTPCodecTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+
+	audioTrack, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, 0, "audio", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(audioTrack); err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "m=audio 9 RTP/SAVP ")
+	assert.Contains(t, offer.SDP, "a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:")
+	assert.NotContains(t, offer.SDP, "a=fingerprint")
+	assert.NotContains(t, offer.SDP, "a=setup")
+	assert.NotContains(t, offer.SDP, "m=application")
+
+	// Answers use the profile of the offer instead of the RTP/SAVPF default
+	defaultProfile := SettingEngine{}
+	defaultProfile.EnableSDESKeying()
+	defaultProfileAPI := NewAPI(WithSettingEngine(defaultProfile))
+	defaultProfileAPI.mediaEngine.RegisterDefaultCodecs()
+	defaultProfilePeer, err := defaultProfileAPI.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, defaultProfilePeer.SetRemoteDescription(offer))
+	answer, err := defaultProfilePeer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, answer.SDP, "m=audio 9 RTP/SAVP ")
+	assert.NoError(t, defaultProfilePeer.Close())
+
+	packetRead := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		p, readErr := track.ReadRTP()
+		if readErr != nil {
+			return
+		}
+		assert.Equal(t, []byte{0xAA}, p.Payload)
+		close(packetRead)
+
+		for {
+			if _, readErr = track.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	pair.signal()
+	assert.Contains(t, pcAnswer.LocalDescription().SDP, "a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:")
+	assert.Equal(t, DTLSTransportStateNew, pcAnswer.dtlsTransport.State())
+
+	go func() {
+		for {
+			if routineErr := audioTrack.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 960}); routineErr != nil {
+				return
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+	}()
+	<-packetRead
+
+	pair.close()
+}
+
+func TestPeerConnection_Media_PlainRTP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pair := newMediaTestPair(t, func(s *SettingEngine) {
+		s.EnableInsecurePlainRTP()
+	}, nil)
+	pcOffer, pcAnswer := pair.offer, pair.answer
+
+	// An offer with DTLS-SRTP is rejected
+	dtlsOfferer, err := NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = dtlsOfferer.AddTransceiver(RTPCodecTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+	dtlsOffer, err := dtlsOfferer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainPeer := pair.newPeerConnection(false)
+	assert.Error(t, plainPeer.SetRemoteDescription(dtlsOffer))
+	assert.NoError(t, plainPeer.Close())
+	assert.NoError(t, dtlsOfferer.Close())
+
+	if _, err = pcAnswer.AddTransceiver(RTPCodecTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+
+	audioTrack, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, 0, "audio", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(audioTrack)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "m=audio 9 RTP/AVP ")
+	assert.NotContains(t, offer.SDP, "a=crypto")
+	assert.NotContains(t, offer.SDP, "a=fingerprint")
+	assert.NotContains(t, offer.SDP, "a=setup")
+	assert.NotContains(t, offer.SDP, "m=application")
+
+	packetRead := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		p, readErr := track.ReadRTP()
+		if readErr != nil {
+			return
+		}
+		assert.Equal(t, []byte{0xAA}, p.Payload)
+		close(packetRead)
+
+		for {
+			if _, readErr = track.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	pair.signal()
+	assert.Contains(t, pcAnswer.LocalDescription().SDP, "m=audio 9 RTP/AVP ")
+	assert.Equal(t, DTLSTransportStateNew, pcAnswer.dtlsTransport.State())
+
+	go func() {
+		for {
+			if routineErr := audioTrack.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 960}); routineErr != nil {
+				return
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+	}()
+	<-packetRead
+
+	// RTCP is sent unencrypted as well
+	assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: audioTrack.SSRC()}}))
+	pkts, err := sender.ReadRTCP()
+	assert.NoError(t, err)
+	assert.Len(t, pkts, 1)
+
+	pair.close()
+}
+
 func TestOfferRejectionMissingCodec(t *testing.T) {
 	api := NewAPI()
 	api.mediaEngine.RegisterDefaultCodecs()
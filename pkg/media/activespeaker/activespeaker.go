@@ -0,0 +1,199 @@
+// Package activespeaker detects the dominant speaker of a group of audio tracks
+// from their audio levels, without decoding the audio
+package activespeaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+const (
+	// DefaultWindow is the window levels are averaged over if New is called with a zero window
+	DefaultWindow = time.Second
+
+	// DefaultSwitchDelay is the switch delay used if New is called with a zero switchDelay
+	DefaultSwitchDelay = 500 * time.Millisecond
+
+	// DefaultMargin is the margin in dB used if New is called with a zero margin
+	DefaultMargin = 3
+
+	// silence is the audio level of digital silence in -dBov
+	silence = 127
+)
+
+type level struct {
+	time     time.Time
+	loudness int
+}
+
+// source holds the levels of a source within the window
+type source struct {
+	levels []level
+	sum    int
+}
+
+func (s *source) prune(oldest time.Time) {
+	i := 0
+	for ; i < len(s.levels) && s.levels[i].time.Before(oldest); i++ {
+		s.sum -= s.levels[i].loudness
+	}
+	s.levels = s.levels[i:]
+}
+
+// loudness is the average level of the source in dB above silence
+func (s *source) loudness() float64 {
+	if len(s.levels) == 0 {
+		return 0
+	}
+	return float64(s.sum) / float64(len(s.levels))
+}
+
+// Detector determines the dominant speaker from the audio levels of many sources, for example
+// the ssrc-audio-level of every remote audio track of an SFU. The levels of every source are
+// averaged over a window. The loudest source becomes the speaker, but only once it was louder
+// than the current speaker by margin for switchDelay, so short noises don't cause a switch
+type Detector struct {
+	window      time.Duration
+	switchDelay time.Duration
+	margin      float64
+
+	// now is replaced in tests
+	now func() time.Time
+
+	mu                     sync.Mutex
+	sources                map[string]*source
+	speaker                string
+	candidate              string
+	candidateSince         time.Time
+	onSpeakerChangeHandler func(id string)
+}
+
+// New constructs a new Detector, zero values select DefaultWindow, DefaultSwitchDelay and DefaultMargin
+func New(window, switchDelay time.Duration, margin uint8) *Detector {
+	if window == 0 {
+		window = DefaultWindow
+	}
+	if switchDelay == 0 {
+		switchDelay = DefaultSwitchDelay
+	}
+	if margin == 0 {
+		margin = DefaultMargin
+	}
+
+	return &Detector{
+		window:      window,
+		switchDelay: switchDelay,
+		margin:      float64(margin),
+		now:         time.Now,
+		sources:     map[string]*source{},
+	}
+}
+
+// OnSpeakerChange sets an event handler which is called with the ID of the new speaker.
+// It is called with an empty ID if the speaker was removed and no other source is heard
+func (d *Detector) OnSpeakerChange(f func(id string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onSpeakerChangeHandler = f
+}
+
+// Speaker returns the ID of the current speaker, empty if no source was heard yet
+func (d *Detector) Speaker() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.speaker
+}
+
+// AddLevel adds the audio level of a packet of the source id. Levels should be added as
+// packets arrive, a source that stops sending is treated as silent once its levels left the window
+func (d *Detector) AddLevel(id string, audioLevel media.AudioLevel) {
+	now := d.now()
+	loudness := 0
+	if audioLevel.Level < silence {
+		loudness = silence - int(audioLevel.Level)
+	}
+
+	d.mu.Lock()
+	s, ok := d.sources[id]
+	if !ok {
+		s = &source{}
+		d.sources[id] = s
+	}
+	s.levels = append(s.levels, level{time: now, loudness: loudness})
+	s.sum += loudness
+
+	hdlr, speaker, changed := d.update(now)
+	d.mu.Unlock()
+
+	if changed && hdlr != nil {
+		hdlr(speaker)
+	}
+}
+
+// RemoveSource forgets a source, for example when its track ended. If it was
+// the speaker the loudest remaining source becomes the speaker immediately
+func (d *Detector) RemoveSource(id string) {
+	d.mu.Lock()
+	delete(d.sources, id)
+	if d.candidate == id {
+		d.candidate = ""
+	}
+
+	var hdlr func(string)
+	var speaker string
+	changed := false
+	if d.speaker == id {
+		d.speaker = ""
+		hdlr, speaker, changed = d.update(d.now())
+		if !changed {
+			changed = true
+			hdlr, speaker = d.onSpeakerChangeHandler, ""
+		}
+	}
+	d.mu.Unlock()
+
+	if changed && hdlr != nil {
+		hdlr(speaker)
+	}
+}
+
+// update chooses the speaker, it returns the handler to call if the speaker changed. Callers must hold d.mu
+func (d *Detector) update(now time.Time) (func(string), string, bool) {
+	oldest := now.Add(-d.window)
+
+	loudest, loudestLoudness := "", 0.0
+	for id, s := range d.sources {
+		s.prune(oldest)
+		if l := s.loudness(); l > loudestLoudness || (l == loudestLoudness && l > 0 && id < loudest) {
+			loudest, loudestLoudness = id, l
+		}
+	}
+
+	switch {
+	case loudest == "" || loudest == d.speaker:
+		d.candidate = ""
+		return nil, "", false
+	case d.speaker == "":
+		// Nobody is speaking, there is nothing to wait for
+	case loudestLoudness < d.speakerLoudness()+d.margin:
+		d.candidate = ""
+		return nil, "", false
+	case d.candidate != loudest:
+		d.candidate, d.candidateSince = loudest, now
+		return nil, "", false
+	case now.Sub(d.candidateSince) < d.switchDelay:
+		return nil, "", false
+	}
+
+	d.speaker, d.candidate = loudest, ""
+	return d.onSpeakerChangeHandler, loudest, true
+}
+
+func (d *Detector) speakerLoudness() float64 {
+	if s, ok := d.sources[d.speaker]; ok {
+		return s.loudness()
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package activespeaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetector(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := New(time.Second, 500*time.Millisecond, 3)
+	d.now = func() time.Time { return now }
+
+	var changes []string
+	d.OnSpeakerChange(func(id string) {
+		changes = append(changes, id)
+	})
+
+	// tick adds a level for every source and advances the time by 20ms
+	tick := func(levels map[string]uint8) {
+		for id, level := range levels {
+			d.AddLevel(id, media.AudioLevel{Level: level, Voice: level < 127})
+		}
+		now = now.Add(20 * time.Millisecond)
+	}
+
+	// Silence doesn't select a speaker
+	tick(map[string]uint8{"a": 127, "b": 127})
+	assert.Equal(t, "", d.Speaker())
+
+	// The first source heard becomes the speaker immediately
+	tick(map[string]uint8{"a": 40, "b": 127})
+	assert.Equal(t, "a", d.Speaker())
+	assert.Equal(t, []string{"a"}, changes)
+
+	// A source that is louder but not by the margin doesn't take over
+	for i := 0; i < 100; i++ {
+		tick(map[string]uint8{"a": 40, "b": 38})
+	}
+	assert.Equal(t, "a", d.Speaker())
+
+	// A short noise doesn't take over
+	for i := 0; i < 10; i++ {
+		tick(map[string]uint8{"a": 40, "b": 10})
+	}
+	for i := 0; i < 100; i++ {
+		tick(map[string]uint8{"a": 40, "b": 127})
+	}
+	assert.Equal(t, "a", d.Speaker())
+	assert.Equal(t, []string{"a"}, changes)
+
+	// A source that stays louder by the margin takes over after the switch delay
+	switched := time.Time{}
+	start := now
+	for i := 0; i < 100 && switched.IsZero(); i++ {
+		tick(map[string]uint8{"a": 60, "b": 20})
+		if d.Speaker() == "b" {
+			switched = now
+		}
+	}
+	assert.False(t, switched.IsZero())
+	assert.True(t, switched.Sub(start) >= 500*time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, changes)
+
+	// Removing the speaker selects the remaining source immediately, then nobody
+	d.RemoveSource("b")
+	assert.Equal(t, "a", d.Speaker())
+	d.RemoveSource("a")
+	assert.Equal(t, "", d.Speaker())
+	assert.Equal(t, []string{"a", "b", "a", ""}, changes)
+}
+
+func TestDetectorSourceStopsSending(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := New(0, 0, 0)
+	d.now = func() time.Time { return now }
+
+	d.AddLevel("a", media.AudioLevel{Level: 10})
+	assert.Equal(t, "a", d.Speaker())
+
+	// Once the levels of a left the window b is louder, even with a quiet level
+	for i := 0; i < 100; i++ {
+		now = now.Add(20 * time.Millisecond)
+		d.AddLevel("b", media.AudioLevel{Level: 100})
+	}
+	assert.Equal(t, "b", d.Speaker())
+}
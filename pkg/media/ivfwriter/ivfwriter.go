@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
@@ -15,6 +16,7 @@ type IVFWriter struct {
 	stream       io.Writer
 	fd           *os.File
 	count        uint64
+	ptsOffset    uint64
 	currentFrame []byte
 }
 
@@ -64,6 +66,19 @@ func (i *IVFWriter) writeHeader() error {
 	return err
 }
 
+// SetDelay delays the frames by d, rounded to the 30 fps timebase of the file. Use it to sync
+// with other files, see Track.SyncOffset. It must be called before the first packet is written
+func (i *IVFWriter) SetDelay(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("delay must not be negative")
+	} else if i.count != 0 || len(i.currentFrame) != 0 {
+		return fmt.Errorf("delay must be set before packets are written")
+	}
+
+	i.ptsOffset = uint64((d*30 + time.Second/2) / time.Second)
+	return nil
+}
+
 // WriteRTP adds a new packet and writes the appropriate headers for it
 func (i *IVFWriter) WriteRTP(packet *rtp.Packet) error {
 	if i.stream == nil {
@@ -85,7 +100,7 @@ func (i *IVFWriter) WriteRTP(packet *rtp.Packet) error {
 
 	frameHeader := make([]byte, 12)
 	binary.LittleEndian.PutUint32(frameHeader[0:], uint32(len(i.currentFrame))) // Frame length
-	binary.LittleEndian.PutUint64(frameHeader[4:], i.count)                     // PTS
+	binary.LittleEndian.PutUint64(frameHeader[4:], i.ptsOffset+i.count)         // PTS
 
 	i.count++
 
@@ -2,9 +2,11 @@ package ivfwriter
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/stretchr/testify/assert"
@@ -125,3 +127,21 @@ func TestIVFWriter_AddPacketAndClose(t *testing.T) {
 		}
 	}
 }
+
+func TestIVFWriter_Delay(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer)
+	assert.Nil(err, "IVFWriter should be created")
+
+	assert.Equal(fmt.Errorf("delay must not be negative"), writer.SetDelay(-time.Second))
+	assert.Nil(writer.SetDelay(500*time.Millisecond), "IVFWriter should be delayed before packets are written")
+
+	packet := &rtp.Packet{Header: rtp.Header{Marker: true}, Payload: []byte{0x10, 0xAA, 0xBB, 0xCC}}
+	assert.Nil(writer.WriteRTP(packet))
+	assert.Equal(fmt.Errorf("delay must be set before packets are written"), writer.SetDelay(time.Second))
+
+	// Half a second is 15 frames of the 30 fps timebase
+	assert.Equal(uint64(15), binary.LittleEndian.Uint64(buffer.Bytes()[32+4:]), "The first frame should be delayed")
+}
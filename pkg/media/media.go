@@ -8,6 +8,18 @@ import (
 type Sample struct {
 	Data    []byte
 	Samples uint32
+
+	// AudioLevel is sent with the packets of an audio sample if set
+	AudioLevel *AudioLevel
+}
+
+// AudioLevel is the level of an audio sample as carried by the ssrc-audio-level header extension, RFC 6464
+type AudioLevel struct {
+	// Level is in -dBov between 0 (loudest) and 127 (silence)
+	Level uint8
+
+	// Voice is set if the sample contains speech
+	Voice bool
 }
 
 // Writer defines an interface to handle
@@ -6,6 +6,7 @@ import (
 	"io"
 	"math/rand"
 	"os"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
@@ -15,7 +16,8 @@ const (
 	pageHeaderTypeContinuationOfStream = 0x00
 	pageHeaderTypeBeginningOfStream    = 0x02
 	pageHeaderTypeEndOfStream          = 0x04
-	defaultPreSkip                     = 3840 // 3840 recommanded in the RFC
+	defaultPreSkip                     = 3840  // 3840 recommanded in the RFC
+	granuleRate                        = 48000 // Granule positions always count 48 kHz samples
 	idPageSignature                    = "OpusHead"
 	commentPageSignature               = "OpusTags"
 	pageHeaderSignature                = "OggS"
@@ -33,6 +35,7 @@ type OggWriter struct {
 	previousGranulePosition uint64
 	previousTimestamp       uint32
 	lastPayloadSize         int
+	hasWritten              bool
 }
 
 // New builds a new OGG Opus writer
@@ -161,6 +164,19 @@ func (i *OggWriter) createPage(payload []uint8, headerType uint8, granulePos uin
 	return page
 }
 
+// SetDelay delays the audio by d. Use it to sync with other files, see Track.SyncOffset.
+// It must be called before the first packet is written
+func (i *OggWriter) SetDelay(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("delay must not be negative")
+	} else if i.hasWritten {
+		return fmt.Errorf("delay must be set before packets are written")
+	}
+
+	i.previousGranulePosition += uint64(d * granuleRate / time.Second)
+	return nil
+}
+
 // WriteRTP adds a new packet and writes the appropriate headers for it
 func (i *OggWriter) WriteRTP(packet *rtp.Packet) error {
 	if packet == nil {
@@ -174,6 +190,7 @@ func (i *OggWriter) WriteRTP(packet *rtp.Packet) error {
 	}
 
 	payload := opusPacket.Payload[0:]
+	i.hasWritten = true
 
 	// Should be equivalent to sampleRate * duration
 	if i.previousTimestamp != 0 {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/stretchr/testify/assert"
@@ -124,3 +125,20 @@ func TestOggWriter_AddPacketAndClose(t *testing.T) {
 		}
 	}
 }
+
+func TestOggWriter_Delay(t *testing.T) {
+	assert := assert.New(t)
+
+	writer, err := NewWith(&bytes.Buffer{}, 48000, 2)
+	assert.Nil(err, "OggWriter should be created")
+
+	assert.Equal(fmt.Errorf("delay must not be negative"), writer.SetDelay(-time.Second))
+	assert.Nil(writer.SetDelay(time.Second), "OggWriter should be delayed before packets are written")
+
+	packet := &rtp.Packet{Header: rtp.Header{Timestamp: 1 + 960}, Payload: []byte{0xAA}}
+	assert.Nil(writer.WriteRTP(packet))
+	assert.Equal(fmt.Errorf("delay must be set before packets are written"), writer.SetDelay(time.Second))
+
+	// A second is 48000 samples, followed by the 20ms of the packet
+	assert.Equal(uint64(1+48000+960), writer.previousGranulePosition, "The audio should be delayed")
+}
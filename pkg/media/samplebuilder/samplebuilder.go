@@ -118,3 +118,12 @@ func (s *SampleBuilder) Pop() *media.Sample {
 	}
 	return nil
 }
+
+// PopWithTimestamp is like Pop, but also returns the RTP timestamp of the sample
+func (s *SampleBuilder) PopWithTimestamp() (*media.Sample, uint32) {
+	sample := s.Pop()
+	if sample == nil {
+		return nil, 0
+	}
+	return sample, s.lastPopTimestamp
+}
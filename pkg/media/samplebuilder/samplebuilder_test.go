@@ -117,3 +117,21 @@ func TestSampleBuilderMaxLate(t *testing.T) {
 	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5002, Timestamp: 502}, Payload: []byte{0x02}})
 	assert.Equal(s.Pop(), &media.Sample{Data: []byte{0x02}, Samples: 1}, "Failed to build samples after large gap")
 }
+
+func TestSampleBuilderPopWithTimestamp(t *testing.T) {
+	assert := assert.New(t)
+	s := New(50, &fakeDepacketizer{})
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 1}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 2}, Payload: []byte{0x02}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 2}, Payload: []byte{0x03}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 3, Timestamp: 3}, Payload: []byte{0x04}})
+
+	sample, timestamp := s.PopWithTimestamp()
+	assert.Equal(&media.Sample{Data: []byte{0x02, 0x03}, Samples: 1}, sample)
+	assert.Equal(uint32(2), timestamp)
+
+	sample, timestamp = s.PopWithTimestamp()
+	assert.Nil(sample)
+	assert.Equal(uint32(0), timestamp)
+}
@@ -52,6 +52,15 @@ func (e *InvalidModificationError) Error() string {
 	return fmt.Sprintf("InvalidModificationError: %v", e.Err)
 }
 
+// InvalidCharacterError indicates the string contains invalid characters.
+type InvalidCharacterError struct {
+	Err error
+}
+
+func (e *InvalidCharacterError) Error() string {
+	return fmt.Sprintf("InvalidCharacterError: %v", e.Err)
+}
+
 // SyntaxError indicates the string did not match the expected pattern.
 type SyntaxError struct {
 	Err error
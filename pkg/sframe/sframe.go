@@ -0,0 +1,243 @@
+// +build !js
+
+// Package sframe is a reference end-to-end encryption transform for webrtc.RTPSender.SetTransform
+// and webrtc.RTPReceiver.SetTransform. Frames are encrypted in the format of SFrame, RFC 9605, with
+// the AES_128_GCM_SHA256_128 cipher suite. The frame metadata isn't authenticated, only the header
+package sframe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/pion/webrtc/v2"
+)
+
+const (
+	// cipherSuite is AES_128_GCM_SHA256_128, RFC 9605 Section 4.5
+	cipherSuite = 0x0004
+	keyLength   = 16
+	nonceLength = 12
+)
+
+var (
+	// ErrNoSendKey indicates that Encrypt was called before SetSendKey
+	ErrNoSendKey = errors.New("sframe: no key to encrypt with was set")
+
+	// ErrUnknownKeyID indicates that a frame was encrypted with a key that wasn't added
+	ErrUnknownKeyID = errors.New("sframe: unknown key ID")
+
+	// ErrInvalidHeader indicates that a frame is too short for its header
+	ErrInvalidHeader = errors.New("sframe: invalid header")
+)
+
+type key struct {
+	aead cipher.AEAD
+	salt []byte
+}
+
+// Context holds the keys frames are encrypted and decrypted with. One Context can be used for every
+// RTPSender and RTPReceiver, frames are decrypted with the key identified by their header
+type Context struct {
+	mu        sync.Mutex
+	keys      map[uint64]*key
+	sendKeyID uint64
+	sendKey   *key
+	counter   uint64
+}
+
+// NewContext creates a Context without keys
+func NewContext() *Context {
+	return &Context{keys: map[uint64]*key{}}
+}
+
+// AddKey adds or replaces the key with keyID. The encryption key and salt are derived from baseKey
+func (c *Context) AddKey(keyID uint64, baseKey []byte) error {
+	k, err := deriveKey(keyID, baseKey)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys[keyID] = k
+	if c.sendKey != nil && c.sendKeyID == keyID {
+		c.sendKey = k
+	}
+	return nil
+}
+
+// RemoveKey removes the key with keyID, frames encrypted with it can no longer be decrypted
+func (c *Context) RemoveKey(keyID uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.keys, keyID)
+	if c.sendKeyID == keyID {
+		c.sendKey = nil
+	}
+}
+
+// SetSendKey selects the key frames are encrypted with, it must have been added with AddKey
+func (c *Context) SetSendKey(keyID uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k, ok := c.keys[keyID]
+	if !ok {
+		return ErrUnknownKeyID
+	}
+	c.sendKeyID, c.sendKey = keyID, k
+	return nil
+}
+
+// Encrypt replaces the data of the frame with its SFrame encryption, it is a webrtc.EncodedFrameTransform
+func (c *Context) Encrypt(frame *webrtc.EncodedFrame) error {
+	c.mu.Lock()
+	k, keyID, counter := c.sendKey, c.sendKeyID, c.counter
+	c.counter++
+	c.mu.Unlock()
+
+	if k == nil {
+		return ErrNoSendKey
+	}
+
+	header := marshalHeader(keyID, counter)
+	out := make([]byte, len(header), len(header)+len(frame.Data)+k.aead.Overhead())
+	copy(out, header)
+	frame.Data = k.aead.Seal(out, k.nonce(counter), frame.Data, header)
+	return nil
+}
+
+// Decrypt replaces the data of the frame with its SFrame decryption, it is a webrtc.EncodedFrameTransform
+func (c *Context) Decrypt(frame *webrtc.EncodedFrame) error {
+	keyID, counter, headerLength, err := unmarshalHeader(frame.Data)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	k, ok := c.keys[keyID]
+	c.mu.Unlock()
+	if !ok {
+		return ErrUnknownKeyID
+	}
+
+	header := frame.Data[:headerLength]
+	data, err := k.aead.Open(nil, k.nonce(counter), frame.Data[headerLength:], header)
+	if err != nil {
+		return err
+	}
+	frame.Data = data
+	return nil
+}
+
+// nonce is the salt XORed with the counter, RFC 9605 Section 4.4.3
+func (k *key) nonce(counter uint64) []byte {
+	nonce := make([]byte, nonceLength)
+	binary.BigEndian.PutUint64(nonce[nonceLength-8:], counter)
+	for i := range nonce {
+		nonce[i] ^= k.salt[i]
+	}
+	return nonce
+}
+
+// deriveKey derives the key and salt from the base key, RFC 9605 Section 4.4.2
+func deriveKey(keyID uint64, baseKey []byte) (*key, error) {
+	secret := hkdfExtract(nil, baseKey)
+
+	context := make([]byte, 10)
+	binary.BigEndian.PutUint64(context, keyID)
+	binary.BigEndian.PutUint16(context[8:], cipherSuite)
+
+	block, err := aes.NewCipher(hkdfExpand(secret, append([]byte("SFrame 1.0 Secret key "), context...), keyLength))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &key{aead: aead, salt: hkdfExpand(secret, append([]byte("SFrame 1.0 Secret salt "), context...), nonceLength)}, nil
+}
+
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	// Writes to a hash.Hash never fail
+	_, _ = mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand only supports lengths up to the size of a SHA-256 hash, which is all that is needed
+func hkdfExpand(prk, info []byte, length int) []byte {
+	mac := hmac.New(sha256.New, prk)
+	_, _ = mac.Write(info)
+	_, _ = mac.Write([]byte{0x01})
+	return mac.Sum(nil)[:length]
+}
+
+// marshalHeader encodes the key ID and counter, values below 8 are stored in the config byte, RFC 9605 Section 4.3
+func marshalHeader(keyID, counter uint64) []byte {
+	header := []byte{0}
+	if keyID < 8 {
+		header[0] |= byte(keyID) << 4
+	} else {
+		b := minimalBigEndian(keyID)
+		header[0] |= 0x80 | byte(len(b)-1)<<4
+		header = append(header, b...)
+	}
+
+	if counter < 8 {
+		header[0] |= byte(counter)
+	} else {
+		b := minimalBigEndian(counter)
+		header[0] |= 0x08 | byte(len(b)-1)
+		header = append(header, b...)
+	}
+	return header
+}
+
+func unmarshalHeader(data []byte) (keyID, counter uint64, length int, err error) {
+	if len(data) < 1 {
+		return 0, 0, 0, ErrInvalidHeader
+	}
+	config := data[0]
+	length = 1
+
+	readValue := func(extended bool, value byte) (uint64, error) {
+		if !extended {
+			return uint64(value), nil
+		}
+		n := int(value) + 1
+		if len(data) < length+n {
+			return 0, ErrInvalidHeader
+		}
+		var v uint64
+		for _, b := range data[length : length+n] {
+			v = v<<8 | uint64(b)
+		}
+		length += n
+		return v, nil
+	}
+
+	if keyID, err = readValue(config&0x80 != 0, config>>4&0x07); err != nil {
+		return 0, 0, 0, err
+	}
+	if counter, err = readValue(config&0x08 != 0, config&0x07); err != nil {
+		return 0, 0, 0, err
+	}
+	return keyID, counter, length, nil
+}
+
+func minimalBigEndian(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	i := 0
+	for i < 7 && b[i] == 0 {
+		i++
+	}
+	return b[i:]
+}
@@ -0,0 +1,78 @@
+// +build !js
+
+package sframe
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader(t *testing.T) {
+	testCases := []struct {
+		keyID, counter uint64
+		header         []byte
+	}{
+		{0, 0, []byte{0x00}},
+		{7, 7, []byte{0x77}},
+		{8, 0, []byte{0x80, 0x08}},
+		{0, 0x0100, []byte{0x09, 0x01, 0x00}},
+		{0xFFFF, 0x01020304, []byte{0x9B, 0xFF, 0xFF, 0x01, 0x02, 0x03, 0x04}},
+	}
+
+	for _, testCase := range testCases {
+		header := marshalHeader(testCase.keyID, testCase.counter)
+		assert.Equal(t, testCase.header, header)
+
+		keyID, counter, length, err := unmarshalHeader(append(header, 0xAA))
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.keyID, keyID)
+		assert.Equal(t, testCase.counter, counter)
+		assert.Equal(t, len(header), length)
+	}
+
+	_, _, _, err := unmarshalHeader([]byte{0x9B, 0xFF})
+	assert.Equal(t, ErrInvalidHeader, err)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	sender, receiver := NewContext(), NewContext()
+
+	frame := &webrtc.EncodedFrame{Data: []byte{0x01, 0x02, 0x03}}
+	assert.Equal(t, ErrNoSendKey, sender.Encrypt(frame))
+	assert.Equal(t, ErrUnknownKeyID, sender.SetSendKey(1))
+
+	assert.NoError(t, sender.AddKey(1000, []byte("base key")))
+	assert.NoError(t, sender.SetSendKey(1000))
+
+	var encrypted [][]byte
+	for i := 0; i < 10; i++ {
+		frame = &webrtc.EncodedFrame{Data: []byte{0x01, 0x02, 0x03}}
+		assert.NoError(t, sender.Encrypt(frame))
+		assert.NotContains(t, encrypted, frame.Data)
+		encrypted = append(encrypted, frame.Data)
+	}
+
+	frame = &webrtc.EncodedFrame{Data: encrypted[9]}
+	assert.Equal(t, ErrUnknownKeyID, receiver.Decrypt(frame))
+
+	// The same key ID with a different base key fails authentication
+	assert.NoError(t, receiver.AddKey(1000, []byte("other key")))
+	assert.Error(t, receiver.Decrypt(frame))
+
+	assert.NoError(t, receiver.AddKey(1000, []byte("base key")))
+	for _, data := range encrypted {
+		frame = &webrtc.EncodedFrame{Data: data}
+		assert.NoError(t, receiver.Decrypt(frame))
+		assert.Equal(t, []byte{0x01, 0x02, 0x03}, frame.Data)
+	}
+
+	// Modifying the counter in the header fails authentication
+	tampered := append([]byte{}, encrypted[9]...)
+	tampered[3]++
+	assert.Error(t, receiver.Decrypt(&webrtc.EncodedFrame{Data: tampered}))
+
+	receiver.RemoveKey(1000)
+	assert.Equal(t, ErrUnknownKeyID, receiver.Decrypt(&webrtc.EncodedFrame{Data: encrypted[0]}))
+}
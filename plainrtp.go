@@ -0,0 +1,254 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
+	"github.com/pion/transport/packetio"
+)
+
+// plainReadStreamBufferSize limits the packets buffered for a reader, like
+// the SRTP read streams do
+const plainReadStreamBufferSize = 1000 * 1000
+
+// isPlainRTPProfile reports if the transport of a media section is RTP/AVP. pion/sdp
+// doesn't parse RTP/AVPF, so descriptions using it are rejected before they get here
+func isPlainRTPProfile(protos []string) bool {
+	return strings.Join(protos, "/") == "RTP/AVP"
+}
+
+// validatePlainRTPDescription ensures all media sections of a remote session
+// description use unencrypted RTP
+func validatePlainRTPDescription(sd *sdp.SessionDescription) error {
+	for _, media := range sd.MediaDescriptions {
+		if media.MediaName.Media == "application" || media.MediaName.Port.Value == 0 {
+			continue
+		}
+
+		if !isPlainRTPProfile(media.MediaName.Protos) {
+			return fmt.Errorf("plain RTP requires RTP/AVP, media section %s uses %s", media.MediaName.Media, strings.Join(media.MediaName.Protos, "/"))
+		}
+	}
+	return nil
+}
+
+// plainSession demultiplexes the unencrypted packets of an endpoint by SSRC,
+// it mirrors the SRTP sessions of pion/srtp
+type plainSession struct {
+	conn  net.Conn
+	ssrcs func([]byte) ([]uint32, error)
+	log   logging.LeveledLogger
+
+	mu                sync.Mutex
+	readStreams       map[uint32]*plainReadStream
+	readStreamsClosed bool
+
+	newStream chan *plainReadStream
+	closing   chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newPlainSession(conn net.Conn, ssrcs func([]byte) ([]uint32, error), log logging.LeveledLogger) *plainSession {
+	s := &plainSession{
+		conn:        conn,
+		ssrcs:       ssrcs,
+		log:         log,
+		readStreams: map[uint32]*plainReadStream{},
+		newStream:   make(chan *plainReadStream),
+		closing:     make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+	go s.readLoop()
+	return s
+}
+
+func (s *plainSession) readLoop() {
+	defer func() {
+		s.mu.Lock()
+		s.readStreamsClosed = true
+		for _, r := range s.readStreams {
+			if err := r.buffer.Close(); err != nil {
+				s.log.Warnf("Failed to close read stream: %v", err)
+			}
+		}
+		s.mu.Unlock()
+		close(s.closed)
+	}()
+
+	b := make([]byte, receiveMTU)
+	for {
+		n, err := s.conn.Read(b)
+		if err != nil {
+			return
+		}
+
+		ssrcs, err := s.ssrcs(b[:n])
+		if err != nil {
+			s.log.Infof("Failed to parse plain packet: %v", err)
+			continue
+		}
+
+		for _, ssrc := range ssrcs {
+			r, isNew := s.getOrCreateReadStream(ssrc)
+			if r == nil {
+				return // Session has been closed
+			} else if isNew {
+				select {
+				case s.newStream <- r: // Notify AcceptStream
+				case <-s.closing:
+					return
+				}
+			}
+
+			if _, err := r.buffer.Write(b[:n]); err != nil && err != packetio.ErrFull {
+				s.log.Infof("Failed to buffer plain packet: %v", err)
+			}
+		}
+	}
+}
+
+func (s *plainSession) getOrCreateReadStream(ssrc uint32) (*plainReadStream, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readStreamsClosed {
+		return nil, false
+	}
+
+	if r, ok := s.readStreams[ssrc]; ok {
+		return r, false
+	}
+
+	r := &plainReadStream{session: s, ssrc: ssrc, buffer: packetio.NewBuffer()}
+	r.buffer.SetLimitSize(plainReadStreamBufferSize)
+	s.readStreams[ssrc] = r
+	return r, true
+}
+
+func (s *plainSession) removeReadStream(ssrc uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.readStreams, ssrc)
+}
+
+func (s *plainSession) OpenReadStream(ssrc uint32) (readStream, error) {
+	r, _ := s.getOrCreateReadStream(ssrc)
+	if r == nil {
+		return nil, fmt.Errorf("plain session has been closed")
+	}
+	return r, nil
+}
+
+func (s *plainSession) AcceptStream() (readStream, uint32, error) {
+	select {
+	case r := <-s.newStream:
+		return r, r.ssrc, nil
+	case <-s.closed:
+		return nil, 0, fmt.Errorf("plain session has been closed")
+	}
+}
+
+func (s *plainSession) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closing)
+	})
+
+	if err := s.conn.Close(); err != nil {
+		return err
+	}
+
+	<-s.closed
+	return nil
+}
+
+// plainReadStream buffers the packets of one SSRC of a plainSession
+type plainReadStream struct {
+	session *plainSession
+	ssrc    uint32
+	buffer  *packetio.Buffer
+}
+
+func (r *plainReadStream) Read(b []byte) (int, error) {
+	return r.buffer.Read(b)
+}
+
+func (r *plainReadStream) Close() error {
+	if err := r.buffer.Close(); err != nil {
+		return err
+	}
+
+	r.session.removeReadStream(r.ssrc)
+	return nil
+}
+
+// plainRTPSession sends and receives RTP packets without encryption
+type plainRTPSession struct {
+	*plainSession
+}
+
+func newPlainRTPSession(conn net.Conn, log logging.LeveledLogger) plainRTPSession {
+	return plainRTPSession{newPlainSession(conn, func(b []byte) ([]uint32, error) {
+		header := &rtp.Header{}
+		if err := header.Unmarshal(b); err != nil {
+			return nil, err
+		}
+		return []uint32{header.SSRC}, nil
+	}, log)}
+}
+
+func (s plainRTPSession) OpenWriteStream() (rtpWriteStream, error) {
+	return plainRTPWriteStream{s.conn}, nil
+}
+
+type plainRTPWriteStream struct {
+	conn net.Conn
+}
+
+func (w plainRTPWriteStream) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	raw, err := header.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	return w.conn.Write(append(raw, payload...))
+}
+
+// plainRTCPSession sends and receives RTCP packets without encryption. Like
+// SRTCP, a packet is read by the streams of all its destination SSRCs
+type plainRTCPSession struct {
+	*plainSession
+}
+
+func newPlainRTCPSession(conn net.Conn, log logging.LeveledLogger) plainRTCPSession {
+	return plainRTCPSession{newPlainSession(conn, func(b []byte) ([]uint32, error) {
+		pkts, err := rtcp.Unmarshal(b)
+		if err != nil {
+			return nil, err
+		}
+
+		seen := map[uint32]struct{}{}
+		ssrcs := []uint32{}
+		for _, p := range pkts {
+			for _, ssrc := range p.DestinationSSRC() {
+				if _, ok := seen[ssrc]; !ok {
+					seen[ssrc] = struct{}{}
+					ssrcs = append(ssrcs, ssrc)
+				}
+			}
+		}
+		return ssrcs, nil
+	}, log)}
+}
+
+func (s plainRTCPSession) OpenWriteStream() (io.Writer, error) {
+	return s.conn, nil
+}
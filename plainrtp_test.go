@@ -0,0 +1,151 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePlainRTPDescription(t *testing.T) {
+	mediaSection := func(media string, port int, protos ...string) *sdp.MediaDescription {
+		return &sdp.MediaDescription{MediaName: sdp.MediaName{
+			Media:  media,
+			Port:   sdp.RangedPort{Value: port},
+			Protos: protos,
+		}}
+	}
+
+	testCases := []struct {
+		name  string
+		media []*sdp.MediaDescription
+		ok    bool
+	}{
+		{"AVP", []*sdp.MediaDescription{mediaSection("audio", 9, "RTP", "AVP")}, true},
+		{"AVPF", []*sdp.MediaDescription{mediaSection("video", 9, "RTP", "AVPF")}, false},
+		{"SAVPF", []*sdp.MediaDescription{mediaSection("audio", 9, "RTP", "SAVPF")}, false},
+		{"DTLS", []*sdp.MediaDescription{mediaSection("audio", 9, "UDP", "TLS", "RTP", "SAVPF")}, false},
+		{"RejectedSkipped", []*sdp.MediaDescription{mediaSection("audio", 0, "UDP", "TLS", "RTP", "SAVPF"), mediaSection("video", 9, "RTP", "AVP")}, true},
+		{"ApplicationSkipped", []*sdp.MediaDescription{mediaSection("application", 9, "DTLS", "SCTP"), mediaSection("video", 9, "RTP", "AVP")}, true},
+	}
+
+	for _, testCase := range testCases {
+		err := validatePlainRTPDescription(&sdp.SessionDescription{MediaDescriptions: testCase.media})
+		assert.Equal(t, testCase.ok, err == nil, testCase.name)
+	}
+}
+
+func TestPlainRTPSession(t *testing.T) {
+	ca, cb := net.Pipe()
+	log := logging.NewDefaultLoggerFactory().NewLogger("test")
+
+	sessionA := newPlainRTPSession(ca, log)
+	sessionB := newPlainRTPSession(cb, log)
+
+	writeStream, err := sessionA.OpenWriteStream()
+	assert.NoError(t, err)
+
+	// A stream that was opened before receives its packets
+	opened, err := sessionB.OpenReadStream(1)
+	assert.NoError(t, err)
+
+	go func() {
+		for _, ssrc := range []uint32{1, 2} {
+			if _, writeErr := writeStream.WriteRTP(&rtp.Header{Version: 2, SSRC: ssrc}, []byte{byte(ssrc)}); writeErr != nil {
+				return
+			}
+		}
+	}()
+
+	b := make([]byte, receiveMTU)
+	n, err := opened.Read(b)
+	assert.NoError(t, err)
+	p := &rtp.Packet{}
+	assert.NoError(t, p.Unmarshal(b[:n]))
+	assert.Equal(t, []byte{1}, p.Payload)
+
+	// An unknown SSRC is accepted as a new stream
+	accepted, ssrc, err := sessionB.AcceptStream()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), ssrc)
+	n, err = accepted.Read(b)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Unmarshal(b[:n]))
+	assert.Equal(t, []byte{2}, p.Payload)
+
+	assert.NoError(t, sessionA.Close())
+	assert.NoError(t, sessionB.Close())
+
+	_, err = opened.Read(b)
+	assert.Error(t, err)
+}
+
+func TestDTLSTransport_PlainRTPWithoutICE(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.EnableInsecurePlainRTP()
+	api := NewAPI(WithSettingEngine(s))
+
+	// The remote is a plain UDP socket, like ffmpeg or GStreamer sending RTP
+	remote, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	conn, err := net.DialUDP("udp4", nil, remote.LocalAddr().(*net.UDPAddr))
+	assert.NoError(t, err)
+
+	// No role is needed since there is no handshake
+	transport, err := api.NewDTLSTransportFromConn(conn, DTLSRoleAuto, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, transport.Start(DTLSParameters{}))
+	assert.Equal(t, DTLSTransportStateNew, transport.State())
+
+	receiver, err := api.NewRTPReceiver(RTPCodecTypeAudio, transport)
+	assert.NoError(t, err)
+	assert.NoError(t, receiver.Receive(RTPReceiveParameters{
+		Encodings: RTPDecodingParameters{RTPCodingParameters{SSRC: 1234}},
+	}))
+
+	track, err := NewTrack(DefaultPayloadTypeOpus, 5678, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+	sender, err := api.NewRTPSender(track, transport)
+	assert.NoError(t, err)
+	assert.NoError(t, sender.Send(RTPSendParameters{
+		Encodings: RTPEncodingParameters{RTPCodingParameters{SSRC: 5678, PayloadType: DefaultPayloadTypeOpus}},
+	}))
+
+	// Packets from the remote are read unencrypted
+	raw, err := (&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 1234, PayloadType: DefaultPayloadTypeOpus}, Payload: []byte{0xAA}}).Marshal()
+	assert.NoError(t, err)
+	_, err = remote.WriteTo(raw, conn.LocalAddr())
+	assert.NoError(t, err)
+
+	p, err := receiver.Track().ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1234), p.SSRC)
+	assert.Equal(t, []byte{0xAA}, p.Payload)
+
+	// and the remote reads the packets sent as plain RTP
+	assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xBB}, Samples: 960}))
+	b := make([]byte, receiveMTU)
+	n, _, err := remote.ReadFrom(b)
+	assert.NoError(t, err)
+	received := &rtp.Packet{}
+	assert.NoError(t, received.Unmarshal(b[:n]))
+	assert.Equal(t, uint32(5678), received.SSRC)
+	assert.Equal(t, []byte{0xBB}, received.Payload)
+
+	assert.NoError(t, sender.Stop())
+	assert.NoError(t, receiver.Stop())
+	assert.NoError(t, transport.Stop())
+	assert.NoError(t, remote.Close())
+}
@@ -140,9 +140,10 @@ func (t *QUICTransport) Start(remoteParameters QUICParameters) error {
 
 func (t *QUICTransport) validateFingerPrint(remoteParameters QUICParameters, remoteCert *x509.Certificate) error {
 	for _, fp := range remoteParameters.Fingerprints {
-		hashAlgo, err := dtls.HashAlgorithmString(fp.Algorithm)
+		hashAlgo, err := dtls.HashAlgorithmString(strings.ToLower(fp.Algorithm))
 		if err != nil {
-			return err
+			// Ignore algorithms we can't compute, another fingerprint may match
+			continue
 		}
 
 		remoteValue, err := dtls.Fingerprint(remoteCert, hashAlgo)
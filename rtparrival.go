@@ -0,0 +1,61 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+// rtpArrivals calls the handler registered for the SSRC of every RTP packet arriving on a
+// DTLSTransport. Remote tracks use it to notice inactivity whether or not the application reads
+// them. Packets are seen before SRTP authenticates them, a forged packet can only delay a mute
+type rtpArrivals struct {
+	mu       sync.RWMutex
+	handlers map[uint32]func()
+}
+
+func (a *rtpArrivals) register(ssrc uint32, f func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.handlers == nil {
+		a.handlers = map[uint32]func(){}
+	}
+	a.handlers[ssrc] = f
+}
+
+func (a *rtpArrivals) unregister(ssrc uint32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.handlers, ssrc)
+}
+
+func (a *rtpArrivals) arrived(b []byte) {
+	if len(b) < 12 {
+		return
+	}
+
+	a.mu.RLock()
+	hdlr := a.handlers[binary.BigEndian.Uint32(b[8:])]
+	a.mu.RUnlock()
+
+	if hdlr != nil {
+		hdlr()
+	}
+}
+
+// arrivalConn reports the RTP packets read from an endpoint to rtpArrivals
+type arrivalConn struct {
+	net.Conn
+	arrivals *rtpArrivals
+}
+
+func (c *arrivalConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if err == nil {
+		c.arrivals.arrived(b[:n])
+	}
+	return n, err
+}
@@ -0,0 +1,69 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math"
+	"time"
+)
+
+// rtpSourceTimeout is how long a source is reported after its last packet
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpreceiver-getcontributingsources
+const rtpSourceTimeout = 10 * time.Second
+
+// RTPContributingSource describes a source that contributed to the packets of an RTPReceiver
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpcontributingsource
+type RTPContributingSource struct {
+	// Timestamp is the time the last packet from the source was read
+	Timestamp time.Time
+
+	// Source is the CSRC or SSRC of the source
+	Source uint32
+
+	// AudioLevel is between 0 (silence) and 1 (0 dBov). It is nil unless the last packet carried
+	// the audio level of the source, RFC 6464 for the SSRC and RFC 6465 for CSRCs
+	AudioLevel *float64
+
+	// RTPTimestamp is the RTP timestamp of the last packet from the source
+	RTPTimestamp uint32
+}
+
+// RTPSynchronizationSource describes the SSRC of the packets of an RTPReceiver
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpsynchronizationsource
+type RTPSynchronizationSource struct {
+	RTPContributingSource
+
+	// VoiceActivityFlag is the V bit of the RFC 6464 audio level, nil if the last packet didn't carry one
+	VoiceActivityFlag *bool
+}
+
+// rtpSource is the state kept for a source as packets are read
+type rtpSource struct {
+	timestamp     time.Time
+	rtpTimestamp  uint32
+	packets       uint32
+	audioLevel    uint8
+	hasAudioLevel bool
+	voiceActivity bool
+}
+
+func (s *rtpSource) contributingSource(source uint32) RTPContributingSource {
+	c := RTPContributingSource{
+		Timestamp:    s.timestamp,
+		Source:       source,
+		RTPTimestamp: s.rtpTimestamp,
+	}
+	if s.hasAudioLevel {
+		audioLevel := linearAudioLevel(s.audioLevel)
+		c.AudioLevel = &audioLevel
+	}
+	return c
+}
+
+// linearAudioLevel converts an audio level in -dBov to the linear scale of the W3C API, 127 is silence
+func linearAudioLevel(level uint8) float64 {
+	if level >= 127 {
+		return 0
+	}
+	return math.Pow(10, -float64(level)/20)
+}
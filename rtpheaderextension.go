@@ -0,0 +1,133 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+// URIs of the RTP header extensions supported by Pion WebRTC
+const (
+	// AudioLevelExtensionURI is the client-to-mixer audio level of the packet's SSRC, RFC 6464
+	AudioLevelExtensionURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
+	// CSRCAudioLevelExtensionURI is the mixer-to-client audio level of every CSRC of the packet, RFC 6465
+	CSRCAudioLevelExtensionURI = "urn:ietf:params:rtp-hdrext:csrc-audio-level"
+)
+
+// RTPHeaderExtensionParameter maps the URI of a header extension to the ID negotiated for it
+// http://draft.ortc.org/#dom-rtcrtpheaderextensionparameters
+type RTPHeaderExtensionParameter struct {
+	URI string
+	ID  int
+}
+
+// supportedHeaderExtensions are offered in the order of the IDs they are offered with
+var supportedHeaderExtensions = map[RTPCodecType][]string{
+	RTPCodecTypeAudio: {AudioLevelExtensionURI, CSRCAudioLevelExtensionURI},
+}
+
+func isSupportedHeaderExtension(kind RTPCodecType, uri string) bool {
+	for _, supported := range supportedHeaderExtensions[kind] {
+		if supported == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// headerExtensionsFromMediaDescription returns the a=extmap values of a media section
+func headerExtensionsFromMediaDescription(md *sdp.MediaDescription) []RTPHeaderExtensionParameter {
+	var extensions []RTPHeaderExtensionParameter
+	for _, attr := range md.Attributes {
+		if attr.Key != "extmap" {
+			continue
+		}
+
+		// a=extmap:<value>["/"<direction>] <URI> <extensionattributes>
+		split := strings.Fields(attr.Value)
+		if len(split) < 2 {
+			continue
+		}
+
+		id, err := strconv.Atoi(strings.SplitN(split[0], "/", 2)[0])
+		if err != nil {
+			continue
+		}
+		extensions = append(extensions, RTPHeaderExtensionParameter{URI: split[1], ID: id})
+	}
+	return extensions
+}
+
+// headerExtensionID returns the ID negotiated for uri, zero if it wasn't negotiated
+func headerExtensionID(extensions []RTPHeaderExtensionParameter, uri string) uint8 {
+	for _, extension := range extensions {
+		if extension.URI == uri {
+			return uint8(extension.ID)
+		}
+	}
+	return 0
+}
+
+// rtpHeaderExtension returns the data of the header extension element with id in a marshaled
+// RTP packet without allocating. One-byte and two-byte headers are supported, RFC 8285 Section 4
+func rtpHeaderExtension(b []byte, id uint8) ([]byte, bool) {
+	if id == 0 || len(b) < 12 || b[0]&0x10 == 0 {
+		return nil, false
+	}
+
+	offset := 12 + int(b[0]&0x0F)*4
+	if len(b) < offset+4 {
+		return nil, false
+	}
+	profile := binary.BigEndian.Uint16(b[offset:])
+	length := int(binary.BigEndian.Uint16(b[offset+2:])) * 4
+	offset += 4
+	if len(b) < offset+length {
+		return nil, false
+	}
+	extension := b[offset : offset+length]
+
+	oneByte := profile == 0xBEDE
+	if !oneByte && profile&0xFFF0 != 0x1000 {
+		return nil, false
+	}
+
+	for i := 0; i < len(extension); {
+		// Padding between elements
+		if extension[i] == 0 {
+			i++
+			continue
+		}
+
+		var elementID uint8
+		var elementLength int
+		if oneByte {
+			elementID, elementLength = extension[i]>>4, int(extension[i]&0x0F)+1
+			if elementID == 15 {
+				// Reserved, processing stops here
+				return nil, false
+			}
+			i++
+		} else {
+			if i+1 >= len(extension) {
+				return nil, false
+			}
+			elementID, elementLength = extension[i], int(extension[i+1])
+			i += 2
+		}
+
+		if i+elementLength > len(extension) {
+			return nil, false
+		}
+		if elementID == id {
+			return extension[i : i+elementLength], true
+		}
+		i += elementLength
+	}
+	return nil, false
+}
@@ -1,6 +1,16 @@
+// +build !js
+
 package webrtc
 
 // RTPReceiveParameters contains the RTP stack settings used by receivers
 type RTPReceiveParameters struct {
 	Encodings RTPDecodingParameters
+
+	// Codecs are the codecs the remote may send, with the payload types that were negotiated
+	// for them. When there is exactly one the Track's codec is known before any media arrives.
+	Codecs []*RTPCodec
+
+	// HeaderExtensions are the header extensions negotiated with the remote, audio levels
+	// are read from them for GetContributingSources and GetSynchronizationSources
+	HeaderExtensions []RTPHeaderExtensionParameter
 }
@@ -3,13 +3,41 @@
 package webrtc
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/pion/rtcp"
-	"github.com/pion/srtp"
+	"github.com/pion/transport/packetio"
+	"github.com/pion/webrtc/v2/pkg/media"
 )
 
+// rtcpBufferSize limits the RTCP an RTPReceiver buffers for the application, like the SRTP read streams do
+const rtcpBufferSize = 1000 * 1000
+
+// receiveBufferPool holds the buffers used by the convenience read methods. Packets never
+// reference a pooled buffer, their data is copied out before the buffer is returned
+var receiveBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new([receiveMTU]byte)
+	},
+}
+
+// readIntoPooledBuffer calls read with a pooled buffer and returns a copy of the data read
+func readIntoPooledBuffer(read func([]byte) (int, error)) ([]byte, error) {
+	buf := receiveBufferPool.Get().(*[receiveMTU]byte)
+	defer receiveBufferPool.Put(buf)
+
+	n, err := read(buf[:])
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf[:n]...), nil
+}
+
 // RTPReceiver allows an application to inspect the receipt of a Track
 type RTPReceiver struct {
 	kind      RTPCodecType
@@ -20,8 +48,30 @@ type RTPReceiver struct {
 	closed, received chan interface{}
 	mu               sync.RWMutex
 
-	rtpReadStream  *srtp.ReadStreamSRTP
-	rtcpReadStream *srtp.ReadStreamSRTCP
+	rtpReadStream  readStream
+	rtcpReadStream readStream
+
+	// rtcpBuffer holds the RTCP read by readRTCPLoop until the application reads it
+	rtcpBuffer *packetio.Buffer
+
+	rtpReader, rtcpReader *interruptibleReader
+	rtcpReadDeadline      readDeadline
+
+	codecs []*RTPCodec
+
+	// transform is applied to the samples read from the Track with ReadSample
+	transform EncodedFrameTransform
+
+	// The sources of the packets read, audio levels are read from the negotiated header extensions
+	sourcesMu             sync.Mutex
+	synchronizationSource rtpSource
+	contributingSources   map[uint32]*rtpSource
+	audioLevelID          uint8
+	csrcAudioLevelID      uint8
+
+	// onStoppedHdlr is called once the RTPReceiver is stopped, the PeerConnection forgets an adopted
+	// UnhandledStream with it
+	onStoppedHdlr func()
 
 	// A reference to the associated api object
 	api *API
@@ -33,13 +83,30 @@ func (api *API) NewRTPReceiver(kind RTPCodecType, transport *DTLSTransport) (*RT
 		return nil, fmt.Errorf("DTLSTransport must not be nil")
 	}
 
-	return &RTPReceiver{
+	r := &RTPReceiver{
 		kind:      kind,
 		transport: transport,
 		api:       api,
 		closed:    make(chan interface{}),
 		received:  make(chan interface{}),
-	}, nil
+	}
+	r.rtpReader = newInterruptibleReader(func(b []byte) (int, error) {
+		select {
+		case <-r.received:
+			return r.rtpReadStream.Read(b)
+		case <-r.closed:
+			return 0, io.EOF
+		}
+	})
+	r.rtcpReader = newInterruptibleReader(func(b []byte) (int, error) {
+		select {
+		case <-r.received:
+			return r.rtcpBuffer.Read(b)
+		case <-r.closed:
+			return 0, io.EOF
+		}
+	})
+	return r, nil
 }
 
 // Transport returns the currently-configured *DTLSTransport or nil
@@ -66,13 +133,27 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 		return fmt.Errorf("Receive has already been called")
 	default:
 	}
-	close(r.received)
 
+	r.codecs = parameters.Codecs
+	r.audioLevelID = headerExtensionID(parameters.HeaderExtensions, AudioLevelExtensionURI)
+	r.csrcAudioLevelID = headerExtensionID(parameters.HeaderExtensions, CSRCAudioLevelExtensionURI)
 	r.track = &Track{
 		kind:     r.kind,
 		ssrc:     parameters.Encodings.SSRC,
 		receiver: r,
 	}
+	// telephone-event is sent next to the media, it doesn't leave a choice of codec
+	var mediaCodecs []*RTPCodec
+	for _, codec := range r.codecs {
+		if codec.Name != TelephoneEvent {
+			mediaCodecs = append(mediaCodecs, codec)
+		}
+	}
+	if len(mediaCodecs) == 1 {
+		r.track.codec = mediaCodecs[0]
+		r.track.kind = mediaCodecs[0].Type
+		r.track.payloadType = mediaCodecs[0].PayloadType
+	}
 
 	srtpSession, err := r.transport.getSRTPSession()
 	if err != nil {
@@ -94,18 +175,80 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 		return err
 	}
 
+	r.track.startInactivityTimer(r.api.settingEngine.getTrackInactivityTimeout())
+	r.transport.rtpArrivals.register(parameters.Encodings.SSRC, r.track.packetArrived)
+
+	r.rtcpBuffer = packetio.NewBuffer()
+	r.rtcpBuffer.SetLimitSize(rtcpBufferSize)
+	go r.readRTCPLoop(r.rtcpReadStream, r.rtcpBuffer)
+
+	// Readers are waiting for the streams
+	close(r.received)
 	return nil
 }
 
-// Read reads incoming RTCP for this RTPReceiver
+// readRTCPLoop reads the RTCP of the track as it arrives, so sender reports and BYE are handled whether
+// or not the application reads RTCP. Packets are buffered for Read, they are dropped once the buffer is full
+func (r *RTPReceiver) readRTCPLoop(stream readStream, buffer *packetio.Buffer) {
+	defer func() {
+		if err := buffer.Close(); err != nil {
+			r.api.settingEngine.LoggerFactory.NewLogger("RTPReceiver").Warnf("Failed to close RTCP buffer: %v", err)
+		}
+	}()
+
+	b := make([]byte, receiveMTU)
+	for {
+		n, err := stream.Read(b)
+		if err != nil {
+			return
+		}
+
+		r.handleRTCP(b[:n])
+		if _, err := buffer.Write(b[:n]); err != nil && err != packetio.ErrFull {
+			return
+		}
+	}
+}
+
+// Read reads incoming RTCP for this RTPReceiver. It blocks until Receive is called,
+// and returns io.EOF once the RTPReceiver is stopped
 func (r *RTPReceiver) Read(b []byte) (n int, err error) {
-	<-r.received
-	return r.rtcpReadStream.Read(b)
+	return r.rtcpReader.Read(b, r.rtcpReadDeadline.wait(), context.Background())
+}
+
+// ReadContext is like Read, but returns ctx.Err() if ctx is done before a packet is read
+func (r *RTPReceiver) ReadContext(ctx context.Context, b []byte) (n int, err error) {
+	return r.rtcpReader.Read(b, r.rtcpReadDeadline.wait(), ctx)
 }
 
-// ReadRTCP is a convenience method that wraps Read and unmarshals for you
+// SetReadDeadline sets the deadline for Read, ReadRTCP and their context variants. Like with net.Conn it
+// also applies to reads blocked at that time, they return ErrDeadlineExceeded. A zero value disables the deadline
+func (r *RTPReceiver) SetReadDeadline(t time.Time) error {
+	r.rtcpReadDeadline.set(t)
+	return nil
+}
+
+// ReadRTCP is a convenience method that wraps Read and unmarshals for you.
+// It allocates a copy of the bytes read for the packets returned, see ReadRTCPInto
 func (r *RTPReceiver) ReadRTCP() ([]rtcp.Packet, error) {
-	b := make([]byte, receiveMTU)
+	return r.ReadRTCPContext(context.Background())
+}
+
+// ReadRTCPContext is like ReadRTCP, but returns ctx.Err() if ctx is done before a packet is read
+func (r *RTPReceiver) ReadRTCPContext(ctx context.Context) ([]rtcp.Packet, error) {
+	b, err := readIntoPooledBuffer(func(b []byte) (int, error) {
+		return r.rtcpReader.Read(b, r.rtcpReadDeadline.wait(), ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rtcp.Unmarshal(b)
+}
+
+// ReadRTCPInto reads a single compound packet into b and unmarshals it. Unlike ReadRTCP it doesn't
+// copy the bytes read, the packets returned may reference b, so b must not be reused while they are in use
+func (r *RTPReceiver) ReadRTCPInto(b []byte) ([]rtcp.Packet, error) {
 	i, err := r.Read(b)
 	if err != nil {
 		return nil, err
@@ -114,34 +257,231 @@ func (r *RTPReceiver) ReadRTCP() ([]rtcp.Packet, error) {
 	return rtcp.Unmarshal(b[:i])
 }
 
-// Stop irreversibly stops the RTPReceiver
+// SetTransform sets a transform which is called with every frame read from the Track with ReadSample,
+// for example to decrypt it. Frames the transform returns an error for are dropped. Only frames of VP8,
+// Opus and G722 can be depacketized, see ReadSample. A nil transform removes it
+func (r *RTPReceiver) SetTransform(transform EncodedFrameTransform) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transform = transform
+}
+
+func (r *RTPReceiver) getTransform() EncodedFrameTransform {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.transform
+}
+
+// GetContributingSources returns the CSRCs of the packets read from the Track in the last 10 seconds
+func (r *RTPReceiver) GetContributingSources() []RTPContributingSource {
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	sources := []RTPContributingSource{}
+	for csrc, source := range r.contributingSources {
+		if time.Since(source.timestamp) <= rtpSourceTimeout {
+			sources = append(sources, source.contributingSource(csrc))
+		}
+	}
+	return sources
+}
+
+// GetSynchronizationSources returns the SSRC of the Track if a packet was read from it in the last 10 seconds
+func (r *RTPReceiver) GetSynchronizationSources() []RTPSynchronizationSource {
+	track := r.Track()
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	sources := []RTPSynchronizationSource{}
+	source := r.synchronizationSource
+	if track == nil || source.packets == 0 || time.Since(source.timestamp) > rtpSourceTimeout {
+		return sources
+	}
+
+	s := RTPSynchronizationSource{RTPContributingSource: source.contributingSource(track.SSRC())}
+	if source.hasAudioLevel {
+		voiceActivity := source.voiceActivity
+		s.VoiceActivityFlag = &voiceActivity
+	}
+	return append(sources, s)
+}
+
+// audioLevel returns the ssrc-audio-level of the last packet read
+func (r *RTPReceiver) audioLevel() (media.AudioLevel, bool) {
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	source := r.synchronizationSource
+	return media.AudioLevel{Level: source.audioLevel, Voice: source.voiceActivity}, source.hasAudioLevel
+}
+
+// packetReceived updates the sources with a packet read from the Track
+func (r *RTPReceiver) packetReceived(b []byte) {
+	if len(b) < 12 {
+		return
+	}
+	now := time.Now()
+	timestamp := binary.BigEndian.Uint32(b[4:])
+
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	r.synchronizationSource.timestamp = now
+	r.synchronizationSource.rtpTimestamp = timestamp
+	r.synchronizationSource.packets++
+	r.synchronizationSource.hasAudioLevel = false
+	if level, ok := rtpHeaderExtension(b, r.audioLevelID); ok && len(level) >= 1 {
+		r.synchronizationSource.audioLevel = level[0] & 0x7F
+		r.synchronizationSource.voiceActivity = level[0]&0x80 != 0
+		r.synchronizationSource.hasAudioLevel = true
+	}
+
+	csrcCount := int(b[0] & 0x0F)
+	if csrcCount == 0 || len(b) < 12+csrcCount*4 {
+		return
+	}
+	levels, _ := rtpHeaderExtension(b, r.csrcAudioLevelID)
+
+	if r.contributingSources == nil {
+		r.contributingSources = map[uint32]*rtpSource{}
+	}
+	for i := 0; i < csrcCount; i++ {
+		csrc := binary.BigEndian.Uint32(b[12+i*4:])
+		source, ok := r.contributingSources[csrc]
+		if !ok {
+			source = &rtpSource{}
+			r.contributingSources[csrc] = source
+		}
+
+		source.timestamp = now
+		source.rtpTimestamp = timestamp
+		source.packets++
+		source.hasAudioLevel = i < len(levels)
+		if source.hasAudioLevel {
+			source.audioLevel = levels[i] & 0x7F
+		}
+	}
+}
+
+func (r *RTPReceiver) collectStats(collector *statsReportCollector) {
+	track := r.Track()
+	if track == nil {
+		return
+	}
+	ssrc := track.SSRC()
+
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	for csrc, source := range r.contributingSources {
+		collector.Collecting()
+		stats := RTPContributingSourceStats{
+			Timestamp:            statsTimestampNow(),
+			Type:                 StatsTypeCSRC,
+			ID:                   fmt.Sprintf("RTPContributingSource-%d-%d", ssrc, csrc),
+			ContributorSSRC:      csrc,
+			InboundRTPStreamID:   fmt.Sprintf("InboundRTPStream-%d", ssrc),
+			PacketsContributedTo: source.packets,
+		}
+		if source.hasAudioLevel {
+			stats.AudioLevel = linearAudioLevel(source.audioLevel)
+		}
+		collector.Collect(stats.ID, stats)
+	}
+}
+
+// Stop irreversibly stops the RTPReceiver, its Track ends
 func (r *RTPReceiver) Stop() error {
+	track, hdlr, err := r.stop()
+	if track != nil {
+		track.end()
+	}
+	if hdlr != nil {
+		hdlr()
+	}
+	return err
+}
+
+func (r *RTPReceiver) stop() (*Track, func(), error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	select {
 	case <-r.closed:
-		return nil
+		return nil, nil, nil
 	default:
 	}
+	hdlr := r.onStoppedHdlr
+	r.onStoppedHdlr = nil
+
+	// Reads must be unblocked even if closing a stream fails
+	defer func() {
+		close(r.closed)
+		r.rtpReader.close()
+		r.rtcpReader.close()
+	}()
 
+	var err error
 	select {
 	case <-r.received:
-		if err := r.rtcpReadStream.Close(); err != nil {
-			return err
-		}
-		if err := r.rtpReadStream.Close(); err != nil {
-			return err
+		r.transport.rtpArrivals.unregister(r.track.SSRC())
+		err = r.rtcpReadStream.Close()
+		if rtpErr := r.rtpReadStream.Close(); err == nil {
+			err = rtpErr
 		}
 	default:
 	}
 
-	close(r.closed)
-	return nil
+	return r.track, hdlr, err
+}
+
+// handleRTCP updates the track with the sender reports and BYE for its SSRC found in a compound
+// RTCP packet. The packet is scanned in place instead of being unmarshaled
+func (r *RTPReceiver) handleRTCP(b []byte) {
+	ssrc := r.track.SSRC()
+	for len(b) != 0 {
+		var header rtcp.Header
+		if err := header.Unmarshal(b); err != nil {
+			return
+		}
+
+		length := (int(header.Length) + 1) * 4
+		if length > len(b) {
+			return
+		}
+
+		switch header.Type {
+		case rtcp.TypeSenderReport:
+			// SSRC of sender, NTP timestamp and RTP timestamp, RFC 3550 Section 6.4.1
+			if length >= 20 && binary.BigEndian.Uint32(b[4:]) == ssrc {
+				r.track.senderReportReceived(binary.BigEndian.Uint64(b[8:]), binary.BigEndian.Uint32(b[16:]))
+			}
+		case rtcp.TypeGoodbye:
+			for i := 0; i < int(header.Count) && 8+i*4 <= length; i++ {
+				if binary.BigEndian.Uint32(b[4+i*4:]) == ssrc {
+					r.track.end()
+					break
+				}
+			}
+		}
+		b = b[length:]
+	}
 }
 
 // readRTP should only be called by a track, this only exists so we can keep state in one place
-func (r *RTPReceiver) readRTP(b []byte) (n int, err error) {
-	<-r.received
-	return r.rtpReadStream.Read(b)
+func (r *RTPReceiver) readRTP(b []byte, deadline <-chan struct{}, ctx context.Context) (n int, err error) {
+	return r.rtpReader.Read(b, deadline, ctx)
+}
+
+// codecForPayloadType returns the negotiated codec the remote sends with a payload type
+func (r *RTPReceiver) codecForPayloadType(payloadType uint8) (*RTPCodec, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, codec := range r.codecs {
+		if codec.PayloadType == payloadType {
+			return codec, nil
+		}
+	}
+	return nil, ErrCodecNotFound
 }
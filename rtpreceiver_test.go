@@ -0,0 +1,228 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/transport/packetio"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPReceiver_ContributingSources(t *testing.T) {
+	track := &Track{ssrc: 1234}
+	receiver := &RTPReceiver{track: track, audioLevelID: 1, csrcAudioLevelID: 2}
+
+	assert.Empty(t, receiver.GetSynchronizationSources())
+	assert.Empty(t, receiver.GetContributingSources())
+
+	// One-byte header extensions: ssrc-audio-level with the V bit set and a level of 20 -dBov,
+	// csrc-audio-level with silence for the first and 0 -dBov for the second CSRC
+	packet, err := (&rtp.Packet{Header: rtp.Header{
+		Version:          2,
+		Timestamp:        3000,
+		SSRC:             1234,
+		CSRC:             []uint32{5, 6},
+		Extension:        true,
+		ExtensionProfile: 0xBEDE,
+		ExtensionPayload: []byte{0x10, 0x80 | 20, 0x21, 127, 0, 0, 0, 0},
+	}}).Marshal()
+	assert.NoError(t, err)
+	receiver.packetReceived(packet)
+
+	ssrcs := receiver.GetSynchronizationSources()
+	if assert.Len(t, ssrcs, 1) {
+		assert.Equal(t, uint32(1234), ssrcs[0].Source)
+		assert.Equal(t, uint32(3000), ssrcs[0].RTPTimestamp)
+		if assert.NotNil(t, ssrcs[0].AudioLevel) {
+			assert.InDelta(t, 0.1, *ssrcs[0].AudioLevel, 1e-9)
+		}
+		if assert.NotNil(t, ssrcs[0].VoiceActivityFlag) {
+			assert.True(t, *ssrcs[0].VoiceActivityFlag)
+		}
+	}
+
+	levels := map[uint32]float64{}
+	for _, source := range receiver.GetContributingSources() {
+		assert.Equal(t, uint32(3000), source.RTPTimestamp)
+		if assert.NotNil(t, source.AudioLevel) {
+			levels[source.Source] = *source.AudioLevel
+		}
+	}
+	assert.Equal(t, map[uint32]float64{5: 0, 6: 1}, levels)
+
+	// Packets without the extensions clear the audio level
+	packet, err = (&rtp.Packet{Header: rtp.Header{Version: 2, Timestamp: 3960, SSRC: 1234, CSRC: []uint32{5}}}).Marshal()
+	assert.NoError(t, err)
+	receiver.packetReceived(packet)
+
+	ssrcs = receiver.GetSynchronizationSources()
+	if assert.Len(t, ssrcs, 1) {
+		assert.Nil(t, ssrcs[0].AudioLevel)
+		assert.Nil(t, ssrcs[0].VoiceActivityFlag)
+	}
+
+	collector := newStatsReportCollector()
+	receiver.collectStats(collector)
+	report := collector.Ready()
+
+	stats, ok := report["RTPContributingSource-1234-5"].(RTPContributingSourceStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint32(2), stats.PacketsContributedTo)
+		assert.Equal(t, "InboundRTPStream-1234", stats.InboundRTPStreamID)
+	}
+	stats, ok = report["RTPContributingSource-1234-6"].(RTPContributingSourceStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint32(1), stats.PacketsContributedTo)
+		assert.Equal(t, 1.0, stats.AudioLevel)
+	}
+}
+
+func TestRTPHeaderExtension_TwoByte(t *testing.T) {
+	packet, err := (&rtp.Packet{Header: rtp.Header{
+		Version:          2,
+		Extension:        true,
+		ExtensionProfile: 0x1000,
+		ExtensionPayload: []byte{3, 0, 0, 7, 2, 0xAA, 0xBB, 0},
+	}}).Marshal()
+	assert.NoError(t, err)
+
+	data, ok := rtpHeaderExtension(packet, 3)
+	assert.True(t, ok)
+	assert.Empty(t, data)
+
+	data, ok = rtpHeaderExtension(packet, 7)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0xAA, 0xBB}, data)
+
+	_, ok = rtpHeaderExtension(packet, 4)
+	assert.False(t, ok)
+}
+
+func TestRTPReceiver_HeaderExtensionsNegotiation(t *testing.T) {
+	offerer, answerer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	_, err = offerer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=extmap:1 "+AudioLevelExtensionURI)
+	assert.Contains(t, offer.SDP, "a=extmap:2 "+CSRCAudioLevelExtensionURI)
+
+	// Only the supported extensions of the offer are answered, with the IDs of the offer
+	offer.SDP = strings.Replace(offer.SDP, "a=extmap:2 "+CSRCAudioLevelExtensionURI, "a=extmap:5 urn:example:unsupported", 1)
+	offer.SDP = strings.Replace(offer.SDP, "a=extmap:1 ", "a=extmap:3 ", 1)
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+
+	answer, err := answerer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=extmap:3 "+AudioLevelExtensionURI)
+	assert.NotContains(t, answer.SDP, CSRCAudioLevelExtensionURI)
+	assert.NotContains(t, answer.SDP, "urn:example:unsupported")
+	assert.Equal(t, 1, strings.Count(answer.SDP, "a=extmap"))
+
+	assert.NoError(t, offerer.Close())
+	assert.NoError(t, answerer.Close())
+}
+
+// packetStream is a readStream returning packets and then io.EOF
+type packetStream [][]byte
+
+func (s *packetStream) Read(b []byte) (int, error) {
+	if len(*s) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, (*s)[0])
+	*s = (*s)[1:]
+	return n, nil
+}
+
+func (s *packetStream) Close() error {
+	return nil
+}
+
+func TestRTPReceiver_RTCPWithoutReads(t *testing.T) {
+	codec := NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)
+	track := &Track{ssrc: 1234, codec: codec}
+	receiver := &RTPReceiver{track: track}
+
+	senderReport, err := rtcp.Marshal([]rtcp.Packet{&rtcp.SenderReport{SSRC: 1234, NTPTime: uint64(ntpEpochOffset) << 32, RTPTime: 90000}})
+	assert.NoError(t, err)
+	bye, err := rtcp.Marshal([]rtcp.Packet{&rtcp.Goodbye{Sources: []uint32{1234}}})
+	assert.NoError(t, err)
+
+	// The loop handles sender reports and BYE as they arrive, before anything is read
+	buffer := packetio.NewBuffer()
+	stream := packetStream{senderReport, bye}
+	receiver.readRTCPLoop(&stream, buffer)
+
+	at, ok := track.RTPTimestampToTime(90000)
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(0, 0), at)
+	assert.True(t, track.Ended())
+
+	// The packets are still buffered for the application
+	b := make([]byte, receiveMTU)
+	for _, expected := range [][]byte{senderReport, bye} {
+		n, readErr := buffer.Read(b)
+		assert.NoError(t, readErr)
+		assert.Equal(t, expected, b[:n])
+	}
+	_, err = buffer.Read(b)
+	assert.Equal(t, io.EOF, err)
+}
+
+// closeErrorStream is a readStream blocking until it is closed, Close returns err
+type closeErrorStream struct {
+	closed chan struct{}
+	err    error
+}
+
+func (s *closeErrorStream) Read(b []byte) (int, error) {
+	<-s.closed
+	return 0, io.EOF
+}
+
+func (s *closeErrorStream) Close() error {
+	close(s.closed)
+	return s.err
+}
+
+func TestRTPReceiver_StopWithCloseError(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	receiver, err := NewAPI().NewRTPReceiver(RTPCodecTypeVideo, &DTLSTransport{})
+	assert.NoError(t, err)
+	receiver.track = &Track{ssrc: 1234, receiver: receiver}
+
+	rtcpErr, rtpErr := errors.New("rtcp close failed"), errors.New("rtp close failed")
+	rtcpStream := &closeErrorStream{closed: make(chan struct{}), err: rtcpErr}
+	rtpStream := &closeErrorStream{closed: make(chan struct{}), err: rtpErr}
+	receiver.rtcpReadStream, receiver.rtpReadStream = rtcpStream, rtpStream
+	close(receiver.received)
+
+	readErr := make(chan error)
+	go func() {
+		_, err := receiver.track.Read(make([]byte, receiveMTU))
+		readErr <- err
+	}()
+
+	// The first error is returned, but both streams are closed and reads are unblocked
+	assert.Equal(t, rtcpErr, receiver.Stop())
+	assert.Equal(t, io.EOF, <-readErr)
+	_, err = rtpStream.Read(nil)
+	assert.Equal(t, io.EOF, err)
+	assert.True(t, receiver.track.Ended())
+}
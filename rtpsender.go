@@ -3,24 +3,63 @@
 package webrtc
 
 import (
+	"context"
 	"fmt"
+	"io"
+	mathRand "math/rand"
 	"sync"
+	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
-	"github.com/pion/srtp"
+	"github.com/pion/webrtc/v2/pkg/media"
 )
 
 // RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
 type RTPSender struct {
 	track          *Track
-	rtcpReadStream *srtp.ReadStreamSRTCP
+	rtcpReadStream readStream
+
+	rtcpReader       *interruptibleReader
+	rtcpReadDeadline readDeadline
 
 	transport *DTLSTransport
 
 	// A reference to the associated api object
 	api *API
 
+	// ssrc and payloadType are written into every outgoing packet, so a Track can be
+	// sent to peers that negotiated different payload types
+	ssrc        uint32
+	payloadType uint8
+
+	rtpWriteStream rtpWriteStream
+
+	// dtmf is only set for audio, telephoneEvent is the negotiated codec DTMF is sent with
+	dtmf           *DTMFSender
+	telephoneEvent *RTPCodec
+
+	// transform is applied to the samples written to the Track
+	transform EncodedFrameTransform
+
+	// audioLevelID is the negotiated ID of the ssrc-audio-level header extension, zero if it wasn't negotiated
+	audioLevelID uint8
+
+	// sendMu serializes writes, it protects the state below. Sequence numbers are rewritten into the
+	// space of this sender, the offset is recalculated every time the SSRC of the packets written to the Track changes.
+	// header is reused for every packet, the header shared by the senders of the Track isn't modified
+	sendMu             sync.Mutex
+	header             rtp.Header
+	sourceSSRC         uint32
+	sequenceNumberSet  bool
+	sequenceNumberDiff uint16
+	lastSequenceNumber uint16
+	audioLevel         [4]byte
+
+	// lastTimestamp and lastTimestampTime place telephone-events in the timeline of the media
+	lastTimestamp     uint32
+	lastTimestampTime time.Time
+
 	mu                     sync.RWMutex
 	sendCalled, stopCalled chan interface{}
 }
@@ -40,13 +79,38 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) (*RTPSender
 	}
 	track.totalSenderCount++
 
-	return &RTPSender{
-		track:      track,
-		transport:  transport,
-		api:        api,
-		sendCalled: make(chan interface{}),
-		stopCalled: make(chan interface{}),
-	}, nil
+	r := &RTPSender{
+		track:       track,
+		transport:   transport,
+		api:         api,
+		ssrc:        track.ssrc,
+		payloadType: track.payloadType,
+		sendCalled:  make(chan interface{}),
+		stopCalled:  make(chan interface{}),
+	}
+	if track.kind == RTPCodecTypeAudio {
+		r.dtmf = &DTMFSender{sender: r}
+	}
+	r.rtcpReader = newInterruptibleReader(func(b []byte) (int, error) {
+		select {
+		case <-r.sendCalled:
+			for {
+				r.mu.RLock()
+				rtcpReadStream := r.rtcpReadStream
+				r.mu.RUnlock()
+
+				// The stream is replaced when the SSRC changes, continue reading on the new one
+				n, err := rtcpReadStream.Read(b)
+				if err == io.EOF && rtcpReadStream != r.currentRTCPReadStream() {
+					continue
+				}
+				return n, err
+			}
+		case <-r.stopCalled:
+			return 0, io.EOF
+		}
+	})
+	return r, nil
 }
 
 // Transport returns the currently-configured *DTLSTransport or nil
@@ -57,7 +121,82 @@ func (r *RTPSender) Transport() *DTLSTransport {
 	return r.transport
 }
 
+// DTMF returns the DTMFSender that sends DTMF tones with this RTPSender, it is nil for video
+func (r *RTPSender) DTMF() *DTMFSender {
+	return r.dtmf
+}
+
+// SSRC returns the SSRC used for the packets sent by this RTPSender
+func (r *RTPSender) SSRC() uint32 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ssrc
+}
+
+func (r *RTPSender) currentRTCPReadStream() readStream {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.rtcpReadStream
+}
+
+// changeSSRC moves the RTPSender to a new SSRC and returns the previous one. If Send has
+// been called the RTCP stream of the previous SSRC is replaced by one for the new SSRC
+func (r *RTPSender) changeSSRC(ssrc uint32) (uint32, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldSSRC := r.ssrc
+	r.ssrc = ssrc
+
+	select {
+	case <-r.stopCalled:
+		return oldSSRC, nil
+	default:
+	}
+	if !r.hasSent() {
+		return oldSSRC, nil
+	}
+
+	srtcpSession, err := r.transport.getSRTCPSession()
+	if err != nil {
+		return oldSSRC, err
+	}
+
+	rtcpReadStream, err := srtcpSession.OpenReadStream(ssrc)
+	if err != nil {
+		return oldSSRC, err
+	}
+
+	oldRTCPReadStream := r.rtcpReadStream
+	r.rtcpReadStream = rtcpReadStream
+	return oldSSRC, oldRTCPReadStream.Close()
+}
+
+// PayloadType returns the PayloadType used for the packets sent by this RTPSender
+func (r *RTPSender) PayloadType() uint8 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.payloadType
+}
+
+// SetTransform sets a transform which is called with every sample written to the Track with
+// WriteSample before it is packetized for this RTPSender, for example to encrypt it end-to-end.
+// While it is set Write and WriteRTP of the Track return ErrWriteRTPWithTransform, so packets
+// aren't sent untransformed. A nil transform removes it
+func (r *RTPSender) SetTransform(transform EncodedFrameTransform) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transform = transform
+}
+
+func (r *RTPSender) getTransform() EncodedFrameTransform {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.transform
+}
+
 // Send Attempts to set the parameters controlling the sending of media.
+// Packets written to the Track are sent with the SSRC and PayloadType of the encoding.
 func (r *RTPSender) Send(parameters RTPSendParameters) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -76,6 +215,25 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 		return err
 	}
 
+	srtpSession, err := r.transport.getSRTPSession()
+	if err != nil {
+		return err
+	}
+
+	r.rtpWriteStream, err = srtpSession.OpenWriteStream()
+	if err != nil {
+		return err
+	}
+	r.ssrc = parameters.Encodings.SSRC
+	r.payloadType = parameters.Encodings.PayloadType
+	for _, codec := range parameters.Codecs {
+		if codec.Name == TelephoneEvent && r.track.codec != nil && codec.ClockRate == r.track.codec.ClockRate {
+			r.telephoneEvent = codec
+			break
+		}
+	}
+	r.audioLevelID = headerExtensionID(parameters.HeaderExtensions, AudioLevelExtensionURI)
+
 	r.track.mu.Lock()
 	r.track.activeSenders = append(r.track.activeSenders, r)
 	r.track.mu.Unlock()
@@ -107,6 +265,7 @@ func (r *RTPSender) Stop() error {
 	}
 	r.track.activeSenders = filtered
 	close(r.stopCalled)
+	r.rtcpReader.close()
 
 	if r.hasSent() {
 		return r.rtcpReadStream.Close()
@@ -115,15 +274,45 @@ func (r *RTPSender) Stop() error {
 	return nil
 }
 
-// Read reads incoming RTCP for this RTPReceiver
+// Read reads incoming RTCP for this RTPSender. It blocks until Send is called,
+// and returns io.EOF once the RTPSender is stopped
 func (r *RTPSender) Read(b []byte) (n int, err error) {
-	<-r.sendCalled
-	return r.rtcpReadStream.Read(b)
+	return r.rtcpReader.Read(b, r.rtcpReadDeadline.wait(), context.Background())
+}
+
+// ReadContext is like Read, but returns ctx.Err() if ctx is done before a packet is read
+func (r *RTPSender) ReadContext(ctx context.Context, b []byte) (n int, err error) {
+	return r.rtcpReader.Read(b, r.rtcpReadDeadline.wait(), ctx)
+}
+
+// SetReadDeadline sets the deadline for Read, ReadRTCP and their context variants. Like with net.Conn it
+// also applies to reads blocked at that time, they return ErrDeadlineExceeded. A zero value disables the deadline
+func (r *RTPSender) SetReadDeadline(t time.Time) error {
+	r.rtcpReadDeadline.set(t)
+	return nil
 }
 
-// ReadRTCP is a convenience method that wraps Read and unmarshals for you
+// ReadRTCP is a convenience method that wraps Read and unmarshals for you.
+// It allocates a copy of the bytes read for the packets returned, see ReadRTCPInto
 func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, error) {
-	b := make([]byte, receiveMTU)
+	return r.ReadRTCPContext(context.Background())
+}
+
+// ReadRTCPContext is like ReadRTCP, but returns ctx.Err() if ctx is done before a packet is read
+func (r *RTPSender) ReadRTCPContext(ctx context.Context) ([]rtcp.Packet, error) {
+	b, err := readIntoPooledBuffer(func(b []byte) (int, error) {
+		return r.ReadContext(ctx, b)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rtcp.Unmarshal(b)
+}
+
+// ReadRTCPInto reads a single compound packet into b and unmarshals it. Unlike ReadRTCP it doesn't
+// copy the bytes read, the packets returned may reference b, so b must not be reused while they are in use
+func (r *RTPSender) ReadRTCPInto(b []byte) ([]rtcp.Packet, error) {
 	i, err := r.Read(b)
 	if err != nil {
 		return nil, err
@@ -132,24 +321,156 @@ func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, error) {
 	return rtcp.Unmarshal(b[:i])
 }
 
-// sendRTP should only be called by a track, this only exists so we can keep state in one place
-func (r *RTPSender) sendRTP(header *rtp.Header, payload []byte) (int, error) {
+// sendRTP should only be called by a track, this only exists so we can keep state in one place.
+// audioLevel is optional, it is sent if the remote negotiated ssrc-audio-level
+func (r *RTPSender) sendRTP(header *rtp.Header, payload []byte, audioLevel *media.AudioLevel) (int, error) {
 	select {
 	case <-r.stopCalled:
 		return 0, fmt.Errorf("RTPSender has been stopped")
 	case <-r.sendCalled:
-		srtpSession, err := r.transport.getSRTPSession()
-		if err != nil {
-			return 0, err
+		r.sendMu.Lock()
+		defer r.sendMu.Unlock()
+
+		r.rewriteHeader(header, audioLevel)
+		return r.rtpWriteStream.WriteRTP(&r.header, payload)
+	}
+}
+
+// sendFrame transforms a sample and sends it instead of the packets the Track packetized it into.
+// The packets it is sent in take the sequence numbers of the packets of the Track, so packets
+// written to the Track afterwards continue after them
+func (r *RTPSender) sendFrame(transform EncodedFrameTransform, data []byte, packets []*rtp.Packet, audioLevel *media.AudioLevel) error {
+	select {
+	case <-r.stopCalled:
+		return fmt.Errorf("RTPSender has been stopped")
+	case <-r.sendCalled:
+	}
+
+	codec := r.track.Codec()
+	if codec == nil || codec.Payloader == nil {
+		return fmt.Errorf("codec of the Track has no payloader")
+	}
+
+	frame := &EncodedFrame{
+		Data:        append([]byte{}, data...),
+		Keyframe:    isKeyframe(codec, data),
+		Timestamp:   packets[0].Timestamp,
+		SSRC:        r.SSRC(),
+		PayloadType: r.PayloadType(),
+	}
+	if err := transform(frame); err != nil {
+		return err
+	}
+	payloads := codec.Payloader.Payload(rtpOutboundMTU-12, frame.Data)
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	r.rewriteHeader(&packets[0].Header, audioLevel)
+	sequenceNumber := r.header.SequenceNumber
+	for i, payload := range payloads {
+		r.header.Marker = i == len(payloads)-1
+		r.header.SequenceNumber = sequenceNumber + uint16(i)
+		if _, err := r.rtpWriteStream.WriteRTP(&r.header, payload); err != nil {
+			return err
 		}
+	}
+
+	r.lastSequenceNumber = r.header.SequenceNumber
+	r.sequenceNumberDiff = r.lastSequenceNumber - packets[len(packets)-1].SequenceNumber
+	return nil
+}
+
+// rewriteHeader copies header into r.header with the SSRC, PayloadType and SequenceNumber of this RTPSender.
+// The header passed is shared by all senders of the Track and must not be modified. Callers must hold r.sendMu
+func (r *RTPSender) rewriteHeader(header *rtp.Header, audioLevel *media.AudioLevel) {
+	r.mu.RLock()
+	ssrc, payloadType, audioLevelID := r.ssrc, r.payloadType, r.audioLevelID
+	r.mu.RUnlock()
+
+	if !r.sequenceNumberSet || header.SSRC != r.sourceSSRC {
+		// Keep the sequence numbers if the Track already uses our SSRC, otherwise continue
+		// after the last packet sent (or at a random value) so the remote sees a single stream
+		next := uint16(mathRand.Uint32())
+		if r.sequenceNumberSet {
+			next = r.lastSequenceNumber + 1
+		} else if header.SSRC == ssrc {
+			next = header.SequenceNumber
+		}
+
+		r.sourceSSRC = header.SSRC
+		r.sequenceNumberDiff = next - header.SequenceNumber
+		r.sequenceNumberSet = true
+	}
+
+	r.header = *header
+	r.header.SSRC = ssrc
+	r.header.PayloadType = payloadType
+	r.header.SequenceNumber = header.SequenceNumber + r.sequenceNumberDiff
+	r.lastSequenceNumber = r.header.SequenceNumber
 
-		writeStream, err := srtpSession.OpenWriteStream()
-		if err != nil {
-			return 0, err
+	// Packets that already carry extensions are forwarded as they are
+	if audioLevel != nil && audioLevelID != 0 && !header.Extension {
+		// One-byte header, RFC 8285 Section 4.2, with a single element of length one padded to 32 bits
+		r.audioLevel[0] = audioLevelID << 4
+		r.audioLevel[1] = audioLevel.Level & 0x7F
+		if audioLevel.Voice {
+			r.audioLevel[1] |= 0x80
 		}
+		r.header.Extension = true
+		r.header.ExtensionProfile = 0xBEDE
+		r.header.ExtensionPayload = r.audioLevel[:]
+	}
+	if r.dtmf != nil {
+		r.lastTimestamp = header.Timestamp
+		r.lastTimestampTime = time.Now()
+	}
+}
+
+// currentTimestamp returns the RTP timestamp of now, continuing the timestamps of the packets sent.
+// Callers must hold r.sendMu
+func (r *RTPSender) currentTimestamp(clockRate uint32) uint32 {
+	if r.lastTimestampTime.IsZero() {
+		r.lastTimestamp = mathRand.Uint32()
+		r.lastTimestampTime = time.Now()
+	}
+	elapsed := int64(time.Since(r.lastTimestampTime)) * int64(clockRate) / int64(time.Second)
+	return r.lastTimestamp + uint32(elapsed)
+}
+
+// sendTelephoneEvent sends a telephone-event packet between the packets of the Track, it takes the next sequence number
+func (r *RTPSender) sendTelephoneEvent(payloadType uint8, timestamp uint32, marker bool, payload []byte) error {
+	select {
+	case <-r.stopCalled:
+		return fmt.Errorf("RTPSender has been stopped")
+	case <-r.sendCalled:
+	}
+
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	if r.sequenceNumberSet {
+		// Packets of the Track continue after this one
+		r.sequenceNumberDiff++
+	} else {
+		r.lastSequenceNumber = uint16(mathRand.Uint32())
+		r.sequenceNumberSet = true
+	}
 
-		return writeStream.WriteRTP(header, payload)
+	r.header = rtp.Header{
+		Version:        2,
+		Marker:         marker,
+		PayloadType:    payloadType,
+		SequenceNumber: r.lastSequenceNumber + 1,
+		Timestamp:      timestamp,
+		SSRC:           r.SSRC(),
 	}
+	r.lastSequenceNumber = r.header.SequenceNumber
+	_, err := r.rtpWriteStream.WriteRTP(&r.header, payload)
+	return err
 }
 
 // hasSent tells if data has been ever sent for this instance
@@ -1,6 +1,15 @@
+// +build !js
+
 package webrtc
 
 // RTPSendParameters contains the RTP stack settings used by receivers
 type RTPSendParameters struct {
 	Encodings RTPEncodingParameters
+
+	// Codecs are the codecs negotiated for the sender, with the payload types the remote expects.
+	// A telephone-event codec with the clockrate of the Track allows the RTPSender to send DTMF
+	Codecs []*RTPCodec
+
+	// HeaderExtensions are the header extensions negotiated for the sender, with the IDs the remote expects
+	HeaderExtensions []RTPHeaderExtensionParameter
 }
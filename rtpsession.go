@@ -0,0 +1,94 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+
+	"github.com/pion/rtp"
+	"github.com/pion/srtp"
+)
+
+// readStream reads the packets a session received for a single SSRC
+type readStream interface {
+	Read(b []byte) (int, error)
+	Close() error
+}
+
+// rtpWriteStream writes RTP packets to a session
+type rtpWriteStream interface {
+	WriteRTP(header *rtp.Header, payload []byte) (int, error)
+}
+
+// rtpSession carries the RTP packets of a DTLSTransport. It is backed by SRTP,
+// or by plain RTP if SettingEngine.EnableInsecurePlainRTP is used
+type rtpSession interface {
+	OpenReadStream(ssrc uint32) (readStream, error)
+	OpenWriteStream() (rtpWriteStream, error)
+	AcceptStream() (readStream, uint32, error)
+	Close() error
+}
+
+// rtcpSession carries the RTCP packets of a DTLSTransport, see rtpSession
+type rtcpSession interface {
+	OpenReadStream(ssrc uint32) (readStream, error)
+	OpenWriteStream() (io.Writer, error)
+	AcceptStream() (readStream, uint32, error)
+	Close() error
+}
+
+type srtpRTPSession struct {
+	*srtp.SessionSRTP
+}
+
+func (s srtpRTPSession) OpenReadStream(ssrc uint32) (readStream, error) {
+	r, err := s.SessionSRTP.OpenReadStream(ssrc)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s srtpRTPSession) OpenWriteStream() (rtpWriteStream, error) {
+	w, err := s.SessionSRTP.OpenWriteStream()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s srtpRTPSession) AcceptStream() (readStream, uint32, error) {
+	r, ssrc, err := s.SessionSRTP.AcceptStream()
+	if err != nil {
+		return nil, 0, err
+	}
+	return r, ssrc, nil
+}
+
+type srtpRTCPSession struct {
+	*srtp.SessionSRTCP
+}
+
+func (s srtpRTCPSession) OpenReadStream(ssrc uint32) (readStream, error) {
+	r, err := s.SessionSRTCP.OpenReadStream(ssrc)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s srtpRTCPSession) OpenWriteStream() (io.Writer, error) {
+	w, err := s.SessionSRTCP.OpenWriteStream()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s srtpRTCPSession) AcceptStream() (readStream, uint32, error) {
+	r, ssrc, err := s.SessionSRTCP.AcceptStream()
+	if err != nil {
+		return nil, 0, err
+	}
+	return r, ssrc, nil
+}
@@ -23,7 +23,11 @@ func (t *RTPTransceiver) setSendingTrack(track *Track) error {
 		return fmt.Errorf("track must not be nil")
 	}
 
+	t.Sender.mu.Lock()
 	t.Sender.track = track
+	t.Sender.ssrc = track.SSRC()
+	t.Sender.payloadType = track.PayloadType()
+	t.Sender.mu.Unlock()
 
 	switch t.Direction {
 	case RTPTransceiverDirectionRecvonly:
@@ -0,0 +1,126 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+const (
+	// sdesCryptoSuite is the only crypto suite offered and accepted, RFC 4568 Section 6.2.1
+	sdesCryptoSuite = "AES_CM_128_HMAC_SHA1_80"
+	sdesKeyLength   = 16
+	sdesSaltLength  = 14
+
+	// sdesOfferTag is the tag of the a=crypto line of an offer
+	sdesOfferTag = 1
+)
+
+// SDESKeys are the SRTP master keys and salts of both directions of a DTLSTransport keyed with SDES,
+// RFC 4568. They are exchanged out of band, for example in a=crypto lines. Only AES_CM_128_HMAC_SHA1_80
+// is supported, keys are 16 bytes long and salts 14 bytes
+type SDESKeys struct {
+	LocalMasterKey   []byte
+	LocalMasterSalt  []byte
+	RemoteMasterKey  []byte
+	RemoteMasterSalt []byte
+}
+
+// sdesCrypto is the key and salt of one direction of SDES keyed SRTP, RFC 4568
+type sdesCrypto struct {
+	tag       int
+	key, salt []byte
+}
+
+func generateSDESCrypto() (*sdesCrypto, error) {
+	b := make([]byte, sdesKeyLength+sdesSaltLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &sdesCrypto{tag: sdesOfferTag, key: b[:sdesKeyLength], salt: b[sdesKeyLength:]}, nil
+}
+
+// attribute returns the value of the a=crypto line with the given tag
+func (c *sdesCrypto) attribute(tag int) string {
+	return fmt.Sprintf("%d %s inline:%s", tag, sdesCryptoSuite, base64.StdEncoding.EncodeToString(append(append([]byte{}, c.key...), c.salt...)))
+}
+
+// sdesCryptoFromMediaDescription returns the first a=crypto line of a media section with a supported crypto suite.
+// Session parameters aren't supported, lines with them are skipped
+func sdesCryptoFromMediaDescription(md *sdp.MediaDescription) (*sdesCrypto, error) {
+	for _, attr := range md.Attributes {
+		if attr.Key != "crypto" {
+			continue
+		}
+
+		// a=crypto:<tag> <crypto-suite> <key-params> [<session-params>]
+		fields := strings.Fields(attr.Value)
+		if len(fields) != 3 || fields[1] != sdesCryptoSuite || !strings.HasPrefix(fields[2], "inline:") {
+			continue
+		}
+		tag, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		// inline:<key||salt>["|" lifetime]["|" MKI ":" length], MKIs aren't supported
+		keyParams := strings.Split(strings.TrimPrefix(fields[2], "inline:"), "|")
+		if len(keyParams) > 2 || (len(keyParams) == 2 && strings.Contains(keyParams[1], ":")) {
+			continue
+		}
+		keySalt, err := base64.StdEncoding.DecodeString(keyParams[0])
+		if err != nil || len(keySalt) != sdesKeyLength+sdesSaltLength {
+			continue
+		}
+		return &sdesCrypto{tag: tag, key: keySalt[:sdesKeyLength], salt: keySalt[sdesKeyLength:]}, nil
+	}
+
+	return nil, fmt.Errorf("media section %s has no a=crypto line with %s", md.MediaName.Media, sdesCryptoSuite)
+}
+
+// isSecureRTPProfile reports if the transport of a media section is RTP/SAVP or RTP/SAVPF without DTLS
+func isSecureRTPProfile(protos []string) bool {
+	switch strings.Join(protos, "/") {
+	case "RTP/SAVP", "RTP/SAVPF":
+		return true
+	default:
+		return false
+	}
+}
+
+// remoteSDESCrypto returns the remote SRTP key of a session description. All media sections share one
+// SRTP session, so they must use RTP/SAVP or RTP/SAVPF and announce the same key
+func remoteSDESCrypto(sd *sdp.SessionDescription) (*sdesCrypto, error) {
+	var remoteCrypto *sdesCrypto
+	for _, media := range sd.MediaDescriptions {
+		if media.MediaName.Media == "application" || media.MediaName.Port.Value == 0 {
+			continue
+		}
+
+		if !isSecureRTPProfile(media.MediaName.Protos) {
+			return nil, fmt.Errorf("SDES keying requires RTP/SAVP or RTP/SAVPF, media section %s uses %s", media.MediaName.Media, strings.Join(media.MediaName.Protos, "/"))
+		}
+		mediaCrypto, err := sdesCryptoFromMediaDescription(media)
+		if err != nil {
+			return nil, err
+		}
+
+		if remoteCrypto == nil {
+			remoteCrypto = mediaCrypto
+		} else if !bytes.Equal(remoteCrypto.key, mediaCrypto.key) || !bytes.Equal(remoteCrypto.salt, mediaCrypto.salt) {
+			return nil, fmt.Errorf("media sections with different SDES keys are not supported")
+		}
+	}
+
+	if remoteCrypto == nil {
+		return nil, fmt.Errorf("session description has no media section to take the SDES key from")
+	}
+	return remoteCrypto, nil
+}
@@ -0,0 +1,162 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/sdp/v2"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSDESCryptoFromMediaDescription(t *testing.T) {
+	// The bytes 0 to 29 as key and salt
+	const keySalt = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwd"
+
+	testCases := []struct {
+		name  string
+		lines []string
+		tag   int
+		ok    bool
+	}{
+		{"Simple", []string{"1 AES_CM_128_HMAC_SHA1_80 inline:" + keySalt}, 1, true},
+		{"Lifetime", []string{"2 AES_CM_128_HMAC_SHA1_80 inline:" + keySalt + "|2^20"}, 2, true},
+		{"MKI", []string{"1 AES_CM_128_HMAC_SHA1_80 inline:" + keySalt + "|2^20|1:4"}, 0, false},
+		{"MKIWithoutLifetime", []string{"1 AES_CM_128_HMAC_SHA1_80 inline:" + keySalt + "|1:4"}, 0, false},
+		{"SessionParameters", []string{"1 AES_CM_128_HMAC_SHA1_80 inline:" + keySalt + " UNENCRYPTED_SRTCP"}, 0, false},
+		{"UnsupportedSuiteSkipped", []string{"1 AES_256_CM_HMAC_SHA1_80 inline:" + keySalt, "2 AES_CM_128_HMAC_SHA1_80 inline:" + keySalt}, 2, true},
+		{"ShortKey", []string{"1 AES_CM_128_HMAC_SHA1_80 inline:AAAA"}, 0, false},
+		{"None", nil, 0, false},
+	}
+
+	for _, testCase := range testCases {
+		media := &sdp.MediaDescription{MediaName: sdp.MediaName{Media: "audio"}}
+		for _, line := range testCase.lines {
+			media.WithValueAttribute("crypto", line)
+		}
+
+		crypto, err := sdesCryptoFromMediaDescription(media)
+		if !testCase.ok {
+			assert.Error(t, err, testCase.name)
+			continue
+		}
+		if assert.NoError(t, err, testCase.name) {
+			assert.Equal(t, testCase.tag, crypto.tag, testCase.name)
+			assert.Len(t, crypto.key, sdesKeyLength, testCase.name)
+			assert.Len(t, crypto.salt, sdesSaltLength, testCase.name)
+		}
+	}
+
+	local, err := generateSDESCrypto()
+	assert.NoError(t, err)
+	media := (&sdp.MediaDescription{}).WithValueAttribute("crypto", local.attribute(3))
+	parsed, err := sdesCryptoFromMediaDescription(media)
+	assert.NoError(t, err)
+	assert.Equal(t, &sdesCrypto{tag: 3, key: local.key, salt: local.salt}, parsed)
+}
+
+func TestDTLSTransport_SDESWithoutICE(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	api := NewAPI()
+
+	connA, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	connB, err := net.DialUDP("udp4", nil, connA.LocalAddr().(*net.UDPAddr))
+	assert.NoError(t, err)
+
+	// SDES has no handshake, so no DTLS role is needed
+	transportA, err := api.NewDTLSTransportFromPacketConn(connA, connB.LocalAddr(), DTLSRoleAuto, nil)
+	assert.NoError(t, err)
+	transportB, err := api.NewDTLSTransportFromConn(connB, DTLSRoleAuto, nil)
+	assert.NoError(t, err)
+
+	keyA, saltA := make([]byte, sdesKeyLength), make([]byte, sdesSaltLength)
+	keyB, saltB := make([]byte, sdesKeyLength), make([]byte, sdesSaltLength)
+	keyB[0], saltB[0] = 1, 1
+
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrSDESKeyLength}, transportA.StartSDES(SDESKeys{LocalMasterKey: keyA}))
+	assert.NoError(t, transportA.StartSDES(SDESKeys{
+		LocalMasterKey: keyA, LocalMasterSalt: saltA,
+		RemoteMasterKey: keyB, RemoteMasterSalt: saltB,
+	}))
+	assert.NoError(t, transportB.StartSDES(SDESKeys{
+		LocalMasterKey: keyB, LocalMasterSalt: saltB,
+		RemoteMasterKey: keyA, RemoteMasterSalt: saltA,
+	}))
+	assert.Equal(t, DTLSTransportStateNew, transportA.State())
+
+	track, err := NewTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+	sender, err := api.NewRTPSender(track, transportA)
+	assert.NoError(t, err)
+	assert.NoError(t, sender.Send(RTPSendParameters{
+		Encodings: RTPEncodingParameters{RTPCodingParameters{SSRC: 1234, PayloadType: DefaultPayloadTypeOpus}},
+	}))
+
+	receiver, err := api.NewRTPReceiver(RTPCodecTypeAudio, transportB)
+	assert.NoError(t, err)
+	assert.NoError(t, receiver.Receive(RTPReceiveParameters{
+		Encodings: RTPDecodingParameters{RTPCodingParameters{SSRC: 1234}},
+	}))
+
+	// Packets are encrypted on the wire, only the remote with the keys can read them
+	assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 960}))
+	p, err := receiver.Track().ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1234), p.SSRC)
+	assert.Equal(t, []byte{0xAA}, p.Payload)
+
+	assert.NoError(t, sender.Stop())
+	assert.NoError(t, receiver.Stop())
+	assert.NoError(t, transportA.Stop())
+	assert.NoError(t, transportB.Stop())
+}
+
+func TestCertificateVerificationWithoutDTLS(t *testing.T) {
+	verifyErr := &rtcerr.InvalidAccessError{Err: ErrCertificateVerificationWithoutDTLS}
+
+	s := SettingEngine{}
+	s.SetDTLSVerifyPeerCertificate(func(rawCerts [][]byte, fingerprint DTLSFingerprint) error {
+		return nil
+	})
+	s.EnableInsecurePlainRTP()
+
+	// Without a handshake the callback could never be called
+	connA, connB := net.Pipe()
+	transport, err := NewAPI(WithSettingEngine(s)).NewDTLSTransportFromConn(connA, DTLSRoleAuto, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, verifyErr, transport.Start(DTLSParameters{}))
+	assert.Equal(t, verifyErr, transport.StartSDES(SDESKeys{
+		LocalMasterKey: make([]byte, sdesKeyLength), LocalMasterSalt: make([]byte, sdesSaltLength),
+		RemoteMasterKey: make([]byte, sdesKeyLength), RemoteMasterSalt: make([]byte, sdesSaltLength),
+	}))
+	assert.NoError(t, transport.Stop())
+	assert.NoError(t, connB.Close())
+
+	// A PeerConnection with a peer identity refuses SDES keyed descriptions
+	s = SettingEngine{}
+	s.EnableSDESKeying()
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{PeerIdentity: "pion"})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, verifyErr, pcAnswer.SetRemoteDescription(offer))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -3,6 +3,8 @@
 package webrtc
 
 import (
+	"io"
+	"strings"
 	"time"
 
 	"github.com/pion/ice"
@@ -28,11 +30,29 @@ type SettingEngine struct {
 		ICESrflxAcceptanceMinWait    *time.Duration
 		ICEPrflxAcceptanceMinWait    *time.Duration
 		ICERelayAcceptanceMinWait    *time.Duration
+		TrackInactivity              *time.Duration
 	}
 	candidates struct {
 		ICETrickle      bool
 		ICENetworkTypes []NetworkType
 	}
+	sdpMedia struct {
+		CNAME string
+	}
+	dtls struct {
+		SRTPProtectionProfiles []SRTPProtectionProfile
+		VerifyPeerCertificate  func(rawCerts [][]byte, fingerprint DTLSFingerprint) error
+		CertificateStore       *CertificateStore
+		AnsweringRole          DTLSRole
+	}
+	sdes struct {
+		Enabled bool
+		Profile string
+	}
+	insecure struct {
+		PlainRTP bool
+	}
+	keyLog        *keyLogWriter
 	LoggerFactory logging.LoggerFactory
 }
 
@@ -43,6 +63,121 @@ func (e *SettingEngine) DetachDataChannels() {
 	e.detach.DataChannels = true
 }
 
+// SetCNAME sets the RTCP CNAME announced for the tracks of a PeerConnection. By default
+// every PeerConnection generates a random CNAME as recommended by RFC 7022.
+// Tracks that should be synchronized by the remote must be sent with the same CNAME.
+func (e *SettingEngine) SetCNAME(cname string) {
+	e.sdpMedia.CNAME = cname
+}
+
+// SetSRTPProtectionProfiles sets the SRTP protection profiles offered during the DTLS handshake,
+// in order of preference. The profile the handshake selects is used to protect the media.
+// Only the profiles the SRTP and DTLS implementations support are accepted, that is
+// SRTPProtectionProfileAes128CmHmacSha1_80 at the moment. The AEAD-AES-GCM profiles return
+// ErrUnsupportedSRTPProtectionProfile until both implement them.
+func (e *SettingEngine) SetSRTPProtectionProfiles(profiles ...SRTPProtectionProfile) error {
+	if err := validateSRTPProtectionProfiles(profiles); err != nil {
+		return err
+	}
+
+	e.dtls.SRTPProtectionProfiles = profiles
+	return nil
+}
+
+// SetDTLSVerifyPeerCertificate sets a callback that is given the remote DTLS certificate and the
+// fingerprint from the remote description it matched. It runs after the handshake, a returned
+// error fails the DTLSTransport and is reported by DTLSTransport.Err. This allows pinning known
+// certificates in addition to the fingerprint check. rawCerts only holds the leaf certificate,
+// the DTLS implementation doesn't keep the rest of the chain, so verifying against a CA only works
+// if the CA issued the leaf directly. Without DTLS there is no certificate to verify, SDES keying
+// and plain RTP fail with ErrCertificateVerificationWithoutDTLS while it is set.
+func (e *SettingEngine) SetDTLSVerifyPeerCertificate(verify func(rawCerts [][]byte, fingerprint DTLSFingerprint) error) {
+	e.dtls.VerifyPeerCertificate = verify
+}
+
+// SetAnsweringDTLSRole sets the DTLS role taken when answering an offer with setup:actpass,
+// DTLSRoleClient announces setup:active and DTLSRoleServer setup:passive. The default is
+// DTLSRoleClient. Offers declaring setup:active or setup:passive always get the opposite role.
+func (e *SettingEngine) SetAnsweringDTLSRole(role DTLSRole) error {
+	if role != DTLSRoleClient && role != DTLSRoleServer {
+		return ErrAnsweringDTLSRole
+	}
+
+	e.dtls.AnsweringRole = role
+	return nil
+}
+
+// SetCertificateStore makes PeerConnections created without Configuration.Certificates use the
+// current certificate of store instead of generating a new one each. The store rotates the
+// certificate before it expires, PeerConnections keep the certificate they were created with.
+func (e *SettingEngine) SetCertificateStore(store *CertificateStore) {
+	e.dtls.CertificateStore = store
+}
+
+// SetKeyLogWriter writes the secrets of every DTLSTransport to w, so captured traffic can be
+// decrypted offline. Each DTLS handshake writes its master secret as an NSS key log line
+//
+//  CLIENT_RANDOM <hex client random> <hex master secret>
+//
+// which Wireshark loads as (Pre)-Master-Secret log to decrypt DTLS and the data channels in it.
+// Wireshark can't decrypt SRTP, the SRTP master keys and salts are written as key log comments
+// that key log readers skip, one per direction:
+//
+//  # SRTP <protocol> <local address> <remote address> send <crypto suite> inline:<base64 key||salt>
+//  # SRTP <protocol> <local address> <remote address> receive <crypto suite> inline:<base64 key||salt>
+//
+// The send keys protect the packets sent to the remote address, the receive keys the packets
+// received from it. The addresses are those of the ICE candidate pair selected when the keys were
+// derived, or of the connection given to NewDTLSTransportFromConn, '-' if unknown. Crypto suite and
+// key are written like in an SDES a=crypto line, RFC 4568, libsrtp's rtp_decoder decrypts a capture
+// filtered on the addresses when given them as -c <crypto suite> -b <base64 key||salt>. Transports
+// keyed with SDES only write the SRTP lines. Anyone reading w can decrypt the traffic, this is meant
+// for debugging only and a warning is logged for each transport. Pass nil to disable it again.
+func (e *SettingEngine) SetKeyLogWriter(w io.Writer) {
+	if w == nil {
+		e.keyLog = nil
+		return
+	}
+	e.keyLog = &keyLogWriter{w: w}
+}
+
+// EnableSDESKeying exchanges the SRTP keys in a=crypto lines of the session descriptions, RFC 4568,
+// instead of deriving them from a DTLS handshake. It is meant for bridging to SIP endpoints and media
+// servers without DTLS-SRTP. The keys are only as secret as the signaling channel, media sections
+// are offered with the profile set by SetSDESProfile and remote media sections have to use RTP/SAVP
+// or RTP/SAVPF with AES_CM_128_HMAC_SHA1_80. Data channels need DTLS and are rejected.
+// A PeerConnection still requires ICE, the remote has to implement at least ICE lite, RFC 8445
+// Section 2.5. To exchange SDES keyed media with endpoints without ICE, create a DTLSTransport with
+// NewDTLSTransportFromConn and start it with DTLSTransport.StartSDES.
+func (e *SettingEngine) EnableSDESKeying() {
+	e.sdes.Enabled = true
+}
+
+// SetSDESProfile sets the transport profile media sections are offered with if SDES keying is enabled,
+// RTP/SAVPF by default. Use RTP/SAVP for endpoints that don't implement RTCP feedback, RFC 4585.
+// Answers use the profile of the offered media section.
+func (e *SettingEngine) SetSDESProfile(profile string) error {
+	if !isSecureRTPProfile(strings.Split(profile, "/")) {
+		return ErrSDESProfile
+	}
+
+	e.sdes.Profile = profile
+	return nil
+}
+
+// EnableInsecurePlainRTP sends and receives media as plain RTP and RTCP without any encryption or
+// authentication. It is meant for lab testing and for interop with RTP/AVP endpoints only, anyone on
+// the path can read and alter the media. Media sections are offered as RTP/AVP and remote media
+// sections have to use RTP/AVP, RTP/AVPF isn't supported. Data channels need DTLS and are rejected.
+// It takes precedence over EnableSDESKeying.
+//
+// A PeerConnection still requires ICE. To exchange media with endpoints without ICE, like ffmpeg or
+// GStreamer, create a DTLSTransport with NewDTLSTransportFromConn and start it: the handshake is
+// skipped and RTPSenders and RTPReceivers of the transport use plain RTP over the conn.
+func (e *SettingEngine) EnableInsecurePlainRTP() {
+	e.insecure.PlainRTP = true
+}
+
 // SetConnectionTimeout sets the amount of silence needed on a given candidate pair
 // before the ICE agent considers the pair timed out.
 func (e *SettingEngine) SetConnectionTimeout(connectionTimeout, keepAlive time.Duration) {
@@ -75,6 +210,19 @@ func (e *SettingEngine) SetRelayAcceptanceMinWait(t time.Duration) {
 	e.timeout.ICERelayAcceptanceMinWait = &t
 }
 
+// SetTrackInactivityTimeout sets how long a remote Track may go without packets before OnMute
+// is fired. A value of zero disables inactivity detection.
+func (e *SettingEngine) SetTrackInactivityTimeout(t time.Duration) {
+	e.timeout.TrackInactivity = &t
+}
+
+func (e *SettingEngine) getTrackInactivityTimeout() time.Duration {
+	if e.timeout.TrackInactivity == nil {
+		return trackDefaultInactivityTimeout
+	}
+	return *e.timeout.TrackInactivity
+}
+
 // SetEphemeralUDPPortRange limits the pool of ephemeral ports that
 // ICE UDP connections can allocate from. This affects both host candidates,
 // and the local address of server reflexive candidates.
@@ -48,6 +48,20 @@ func TestSetConnectionTimeout(t *testing.T) {
 	}
 }
 
+func TestSetTrackInactivityTimeout(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.getTrackInactivityTimeout() != trackDefaultInactivityTimeout {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	s.SetTrackInactivityTimeout(0)
+
+	if s.getTrackInactivityTimeout() != 0 {
+		t.Fatalf("Track inactivity timeout does not reflect requested value.")
+	}
+}
+
 func TestDetachDataChannels(t *testing.T) {
 	s := SettingEngine{}
 
@@ -61,3 +75,84 @@ func TestDetachDataChannels(t *testing.T) {
 		t.Fatalf("Failed to enable detached data channels.")
 	}
 }
+
+func TestSetCNAME(t *testing.T) {
+	s := SettingEngine{}
+	s.SetCNAME("pion")
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pc.cname != "pion" {
+		t.Fatalf("CNAME does not reflect requested value.")
+	}
+
+	if err = pc.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetSRTPProtectionProfiles(t *testing.T) {
+	s := SettingEngine{}
+
+	if err := s.SetSRTPProtectionProfiles(); err == nil {
+		t.Fatalf("Setting no SRTP protection profiles should fail.")
+	}
+	if err := s.SetSRTPProtectionProfiles(SRTPProtectionProfileAeadAes128Gcm, SRTPProtectionProfileAes128CmHmacSha1_80); err != ErrUnsupportedSRTPProtectionProfile {
+		t.Fatalf("Setting SRTP_AEAD_AES_128_GCM should fail with ErrUnsupportedSRTPProtectionProfile.")
+	}
+	if err := s.SetSRTPProtectionProfiles(SRTPProtectionProfileAeadAes256Gcm); err != ErrUnsupportedSRTPProtectionProfile {
+		t.Fatalf("Setting SRTP_AEAD_AES_256_GCM should fail with ErrUnsupportedSRTPProtectionProfile.")
+	}
+	if len(s.dtls.SRTPProtectionProfiles) != 0 {
+		t.Fatalf("Failed SRTP protection profiles must not be set.")
+	}
+
+	if err := s.SetSRTPProtectionProfiles(SRTPProtectionProfileAes128CmHmacSha1_80); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.dtls.SRTPProtectionProfiles) != 1 || s.dtls.SRTPProtectionProfiles[0] != SRTPProtectionProfileAes128CmHmacSha1_80 {
+		t.Fatalf("SRTP protection profiles do not reflect requested value.")
+	}
+}
+
+func TestSetAnsweringDTLSRole(t *testing.T) {
+	s := SettingEngine{}
+
+	if err := s.SetAnsweringDTLSRole(DTLSRoleAuto); err != ErrAnsweringDTLSRole {
+		t.Fatalf("Setting DTLSRoleAuto should fail with ErrAnsweringDTLSRole.")
+	}
+	if s.dtls.AnsweringRole != DTLSRole(Unknown) {
+		t.Fatalf("Failed answering DTLS role must not be set.")
+	}
+
+	if err := s.SetAnsweringDTLSRole(DTLSRoleServer); err != nil {
+		t.Fatal(err)
+	}
+	if s.dtls.AnsweringRole != DTLSRoleServer {
+		t.Fatalf("Answering DTLS role does not reflect requested value.")
+	}
+}
+
+func TestSetSDESProfile(t *testing.T) {
+	s := SettingEngine{}
+
+	if err := s.SetSDESProfile("RTP/AVP"); err != ErrSDESProfile {
+		t.Fatalf("Setting RTP/AVP should fail with ErrSDESProfile.")
+	}
+	if err := s.SetSDESProfile("UDP/TLS/RTP/SAVPF"); err != ErrSDESProfile {
+		t.Fatalf("Setting UDP/TLS/RTP/SAVPF should fail with ErrSDESProfile.")
+	}
+	if s.sdes.Profile != "" {
+		t.Fatalf("Failed SDES profiles must not be set.")
+	}
+
+	if err := s.SetSDESProfile("RTP/SAVP"); err != nil {
+		t.Fatal(err)
+	}
+	if s.sdes.Profile != "RTP/SAVP" {
+		t.Fatalf("SDES profile does not reflect requested value.")
+	}
+}
@@ -0,0 +1,82 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+
+	"github.com/pion/dtls"
+	"github.com/pion/srtp"
+)
+
+// SRTPProtectionProfile is a DTLS-SRTP protection profile, RFC 5764 Section 4.1.2
+type SRTPProtectionProfile uint16
+
+const (
+	// SRTPProtectionProfileAes128CmHmacSha1_80 is AES-128 in counter mode with an 80 bit HMAC-SHA1 tag, RFC 5764
+	SRTPProtectionProfileAes128CmHmacSha1_80 SRTPProtectionProfile = 0x0001 // nolint
+
+	// SRTPProtectionProfileAeadAes128Gcm is AES-128 in Galois/Counter Mode, RFC 7714. It is not supported yet
+	SRTPProtectionProfileAeadAes128Gcm SRTPProtectionProfile = 0x0007
+
+	// SRTPProtectionProfileAeadAes256Gcm is AES-256 in Galois/Counter Mode, RFC 7714. It is not supported yet
+	SRTPProtectionProfileAeadAes256Gcm SRTPProtectionProfile = 0x0008
+)
+
+// defaultSRTPProtectionProfiles are offered unless SettingEngine.SetSRTPProtectionProfiles is used
+var defaultSRTPProtectionProfiles = []SRTPProtectionProfile{SRTPProtectionProfileAes128CmHmacSha1_80}
+
+// String returns the name of the profile in the IANA DTLS-SRTP protection profile registry
+func (p SRTPProtectionProfile) String() string {
+	switch p {
+	case SRTPProtectionProfileAes128CmHmacSha1_80:
+		return "SRTP_AES128_CM_HMAC_SHA1_80"
+	case SRTPProtectionProfileAeadAes128Gcm:
+		return "SRTP_AEAD_AES_128_GCM"
+	case SRTPProtectionProfileAeadAes256Gcm:
+		return "SRTP_AEAD_AES_256_GCM"
+	default:
+		return unknownStr
+	}
+}
+
+// cryptoSuite returns the name of the profile as an SDES crypto suite, RFC 4568 Section 6.2
+func (p SRTPProtectionProfile) cryptoSuite() string {
+	switch p {
+	case SRTPProtectionProfileAes128CmHmacSha1_80:
+		return "AES_CM_128_HMAC_SHA1_80"
+	default:
+		return unknownStr
+	}
+}
+
+// srtpProfile returns the profile of the SRTP implementation, ok is false if it doesn't implement the profile.
+// The GCM profiles are missing from both the SRTP and the DTLS implementation
+func (p SRTPProtectionProfile) srtpProfile() (profile srtp.ProtectionProfile, ok bool) {
+	switch p {
+	case SRTPProtectionProfileAes128CmHmacSha1_80:
+		return srtp.ProtectionProfileAes128CmHmacSha1_80, true
+	default:
+		return 0, false
+	}
+}
+
+func dtlsSRTPProtectionProfiles(profiles []SRTPProtectionProfile) []dtls.SRTPProtectionProfile {
+	dtlsProfiles := make([]dtls.SRTPProtectionProfile, len(profiles))
+	for i, profile := range profiles {
+		dtlsProfiles[i] = dtls.SRTPProtectionProfile(profile)
+	}
+	return dtlsProfiles
+}
+
+func validateSRTPProtectionProfiles(profiles []SRTPProtectionProfile) error {
+	if len(profiles) == 0 {
+		return fmt.Errorf("at least one SRTP protection profile is required")
+	}
+	for _, profile := range profiles {
+		if _, ok := profile.srtpProfile(); !ok {
+			return ErrUnsupportedSRTPProtectionProfile
+		}
+	}
+	return nil
+}
@@ -247,6 +247,13 @@ func TestPeerConnection_GetStats(t *testing.T) {
 	assert.NotEmpty(t, findRemoteCandidateStats(reportPCAnswer))
 	assert.NotEmpty(t, findCandidatePairStats(t, reportPCAnswer))
 
+	for _, report := range []StatsReport{reportPCOffer, reportPCAnswer} {
+		transportStats, ok := report["DTLSTransport"].(TransportStats)
+		assert.True(t, ok)
+		assert.Equal(t, DTLSTransportStateConnected, transportStats.DTLSState)
+		assert.Equal(t, "SRTP_AES128_CM_HMAC_SHA1_80", transportStats.SRTPCipher)
+	}
+
 	// Close answer DC now
 	dcWait = sync.WaitGroup{}
 	dcWait.Add(1)
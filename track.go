@@ -3,18 +3,25 @@
 package webrtc
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
+	mathRand "math/rand"
 	"sync"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/samplebuilder"
 )
 
 const (
 	rtpOutboundMTU          = 1400
 	trackDefaultIDLength    = 16
 	trackDefaultLabelLength = 16
+
+	trackDefaultInactivityTimeout = 5 * time.Second
 )
 
 // Track represents a single media track
@@ -25,6 +32,7 @@ type Track struct {
 	payloadType uint8
 	kind        RTPCodecType
 	label       string
+	streamIDs   []string
 	ssrc        uint32
 	codec       *RTPCodec
 
@@ -33,6 +41,38 @@ type Track struct {
 	receiver         *RTPReceiver
 	activeSenders    []*RTPSender
 	totalSenderCount int // count of all senders (accounts for senders that have not been started yet)
+
+	// peeked holds a packet that was read to determine the codec, it is returned by the next Read
+	peeked []byte
+
+	readDeadline readDeadline
+
+	// sampleMu serializes ReadSample, the sample builder is replaced when the codec changes
+	sampleMu      sync.Mutex
+	sampleBuilder *samplebuilder.SampleBuilder
+	sampleCodec   *RTPCodec
+
+	// Lifecycle of a remote track, lastPacket is updated as packets arrive on the DTLSTransport
+	inactivityTimeout time.Duration
+	inactivityTimer   *time.Timer
+	lastPacket        time.Time
+	muted, ended      bool
+
+	// Synchronization of a remote track, see RTPTimestampToTime
+	senderReport      senderReport
+	hasSenderReport   bool
+	firstTimestamp    uint32
+	hasFirstTimestamp bool
+
+	// lastDTMFTimestamp identifies the last telephone-event reported, the end of an event is sent more than once
+	lastDTMFTimestamp uint32
+	hasDTMF           bool
+
+	onCodecChangeHandler func(*RTPCodec)
+	onDTMFHandler        func(string, time.Duration)
+	onMuteHandler        func()
+	onUnmuteHandler      func()
+	onEndedHandler       func()
 }
 
 // ID gets the ID of the track
@@ -63,6 +103,24 @@ func (t *Track) Label() string {
 	return t.label
 }
 
+// StreamID gets the ID of the MediaStream the track belongs to. For a remote
+// track it is parsed from the msid the remote announced
+func (t *Track) StreamID() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.streamIDs) == 0 {
+		return ""
+	}
+	return t.streamIDs[0]
+}
+
+// StreamIDs gets the IDs of all MediaStreams the track belongs to
+func (t *Track) StreamIDs() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]string{}, t.streamIDs...)
+}
+
 // SSRC gets the SSRC of the track
 func (t *Track) SSRC() uint32 {
 	t.mu.RLock()
@@ -77,34 +135,459 @@ func (t *Track) Codec() *RTPCodec {
 	return t.codec
 }
 
-// Read reads data from the track. If this is a local track this will error
-func (t *Track) Read(b []byte) (n int, err error) {
+// OnCodecChange sets an event handler which is invoked when the remote starts sending
+// with a different negotiated payload type. It is called from Read before the first
+// packet with the new codec is returned.
+func (t *Track) OnCodecChange(f func(*RTPCodec)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onCodecChangeHandler = f
+}
+
+// OnDTMF sets an event handler which is invoked when a DTMF tone sent as telephone-event ends.
+// It is called from Read with the tone and how long it was played. The telephone-event packets
+// are still returned by Read, and don't change the codec of the track
+func (t *Track) OnDTMF(f func(tone string, duration time.Duration)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onDTMFHandler = f
+}
+
+// OnMute sets an event handler which is invoked when no packet arrived for a remote track for the
+// inactivity timeout configured in the SettingEngine. Packets count as they arrive, whether or not
+// the application reads them
+func (t *Track) OnMute(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onMuteHandler = f
+}
+
+// OnUnmute sets an event handler which is invoked when a packet arrives for a muted remote track again.
+// It is called in its own goroutine
+func (t *Track) OnUnmute(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onUnmuteHandler = f
+}
+
+// OnEnded sets an event handler which is invoked once when a remote track ends, because the remote
+// sent an RTCP BYE for it or its RTPReceiver was stopped, by stopping its RTPTransceiver or closing the
+// PeerConnection. RTCP is processed as it arrives, whether or not the application reads it. Renegotiation
+// doesn't end tracks, SetRemoteDescription can only be called once so a transceiver can't become inactive
+func (t *Track) OnEnded(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onEndedHandler = f
+}
+
+// Muted returns true if no packet arrived for a remote track for the inactivity timeout
+func (t *Track) Muted() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.muted
+}
+
+// Ended returns true once a remote track has ended
+func (t *Track) Ended() bool {
 	t.mu.RLock()
-	if len(t.activeSenders) != 0 {
-		t.mu.RUnlock()
+	defer t.mu.RUnlock()
+	return t.ended
+}
+
+// AudioLevel returns the ssrc-audio-level of the last packet read from a remote track, RFC 6464.
+// It reports false if the extension wasn't negotiated or the packet didn't carry it
+func (t *Track) AudioLevel() (media.AudioLevel, bool) {
+	t.mu.RLock()
+	receiver := t.receiver
+	t.mu.RUnlock()
+
+	if receiver == nil {
+		return media.AudioLevel{}, false
+	}
+	return receiver.audioLevel()
+}
+
+// startInactivityTimer mutes the track if no packet arrives within timeout, zero disables it
+func (t *Track) startInactivityTimer(timeout time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inactivityTimeout = timeout
+	if timeout > 0 {
+		t.lastPacket = time.Now()
+		t.inactivityTimer = time.AfterFunc(timeout, t.checkInactivity)
+	}
+}
+
+// packetArrived is called by the DTLSTransport for every packet of a remote track, before it is read.
+// It unmutes the track and restarts the inactivity timer
+func (t *Track) packetArrived() {
+	t.mu.Lock()
+	if t.ended || t.inactivityTimeout == 0 {
+		t.mu.Unlock()
+		return
+	}
+
+	t.lastPacket = time.Now()
+	unmuted := t.muted
+	if unmuted {
+		t.muted = false
+		t.inactivityTimer.Reset(t.inactivityTimeout)
+	}
+	hdlr := t.onUnmuteHandler
+	t.mu.Unlock()
+
+	// Don't block the transport
+	if unmuted && hdlr != nil {
+		go hdlr()
+	}
+}
+
+// packetReceived updates the first timestamp of the track and the sources of the RTPReceiver with a packet read
+func (t *Track) packetReceived(b []byte) {
+	t.mu.Lock()
+	receiver := t.receiver
+	if !t.hasFirstTimestamp && len(b) >= 8 {
+		t.firstTimestamp = binary.BigEndian.Uint32(b[4:])
+		t.hasFirstTimestamp = true
+	}
+	t.mu.Unlock()
+
+	if receiver != nil {
+		receiver.packetReceived(b)
+	}
+}
+
+// checkInactivity is called by the inactivity timer, it mutes the track if no packet arrived for the
+// timeout and otherwise waits for the rest of it. A muted track is unmuted by packetArrived
+func (t *Track) checkInactivity() {
+	t.mu.Lock()
+	if t.muted || t.ended {
+		t.mu.Unlock()
+		return
+	}
+	if idle := time.Since(t.lastPacket); idle < t.inactivityTimeout {
+		t.inactivityTimer.Reset(t.inactivityTimeout - idle)
+		t.mu.Unlock()
+		return
+	}
+	t.muted = true
+	hdlr := t.onMuteHandler
+	t.mu.Unlock()
+
+	if hdlr != nil {
+		hdlr()
+	}
+}
+
+// end marks the track as ended, OnEnded is only fired the first time
+func (t *Track) end() {
+	t.mu.Lock()
+	if t.ended {
+		t.mu.Unlock()
+		return
+	}
+	t.ended = true
+	if t.inactivityTimer != nil {
+		t.inactivityTimer.Stop()
+	}
+	hdlr := t.onEndedHandler
+	t.mu.Unlock()
+
+	if hdlr != nil {
+		hdlr()
+	}
+}
+
+// Read reads data from the track. If this is a local track this will error.
+// Once the RTPReceiver of the track is stopped Read returns io.EOF
+func (t *Track) Read(b []byte) (n int, err error) {
+	return t.ReadContext(context.Background(), b)
+}
+
+// ReadContext is like Read, but returns ctx.Err() if ctx is done before a packet is read
+func (t *Track) ReadContext(ctx context.Context, b []byte) (n int, err error) {
+	t.mu.Lock()
+	if len(t.activeSenders) != 0 || t.receiver == nil {
+		t.mu.Unlock()
 		return 0, fmt.Errorf("this is a local track and must not be read from")
 	}
 	r := t.receiver
+
+	if peeked := t.peeked; peeked != nil {
+		if len(b) < len(peeked) {
+			t.mu.Unlock()
+			return 0, io.ErrShortBuffer
+		}
+		t.peeked = nil
+		t.mu.Unlock()
+		t.packetReceived(peeked)
+		return copy(b, peeked), nil
+	}
+	t.mu.Unlock()
+
+	n, err = r.readRTP(b, t.readDeadline.wait(), ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	t.packetReceived(b[:n])
+	t.checkPayloadType(b[:n])
+	return n, nil
+}
+
+// RTPTimestampToTime converts an RTP timestamp of a remote track to the wall clock time of
+// the remote using the last RTCP sender report, RFC 3550 Section 6.4.1. It reports false until
+// a sender report was received. Sender reports are handled as they arrive, whether or not the
+// application reads RTCP from the RTPReceiver
+func (t *Track) RTPTimestampToTime(timestamp uint32) (time.Time, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.rtpTimestampToTime(timestamp)
+}
+
+// rtpTimestampToTime is RTPTimestampToTime for callers that hold t.mu
+func (t *Track) rtpTimestampToTime(timestamp uint32) (time.Time, bool) {
+	if !t.hasSenderReport || t.codec == nil || t.codec.ClockRate == 0 {
+		return time.Time{}, false
+	}
+
+	// The difference is signed, so timestamps before the report and wraparound are handled
+	elapsed := int64(int32(timestamp-t.senderReport.rtpTime)) * int64(time.Second) / int64(t.codec.ClockRate)
+	return ntpToTime(t.senderReport.ntpTime).Add(time.Duration(elapsed)), true
+}
+
+// SyncOffset returns how much later the first packet read from t was captured than the first
+// packet read from other. The ivfwriter and oggwriter of pkg/media start every track at zero, pass
+// a positive offset to SetDelay of the writer of t and a negative one, negated, to the writer of
+// other to keep the tracks of a MediaStream in sync. It reports false if the tracks don't share a
+// stream ID, or if a sender report or a packet is missing for either track
+func (t *Track) SyncOffset(other *Track) (time.Duration, bool) {
+	if t.StreamID() == "" || t.StreamID() != other.StreamID() {
+		return 0, false
+	}
+
+	start, ok := t.firstPacketTime()
+	if !ok {
+		return 0, false
+	}
+	otherStart, ok := other.firstPacketTime()
+	if !ok {
+		return 0, false
+	}
+	return start.Sub(otherStart), true
+}
+
+func (t *Track) firstPacketTime() (time.Time, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.hasFirstTimestamp {
+		return time.Time{}, false
+	}
+	return t.rtpTimestampToTime(t.firstTimestamp)
+}
+
+// senderReportReceived stores the mapping of an RTCP sender report for the track
+func (t *Track) senderReportReceived(ntpTime uint64, rtpTime uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.senderReport = senderReport{ntpTime: ntpTime, rtpTime: rtpTime}
+	t.hasSenderReport = true
+}
+
+// senderReport maps a wall clock time of the remote to an RTP timestamp
+type senderReport struct {
+	ntpTime uint64
+	rtpTime uint32
+}
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and the Unix epoch
+const ntpEpochOffset = 2208988800
+
+// ntpToTime converts a 64 bit NTP timestamp, RFC 5905 Section 6
+func ntpToTime(ntpTime uint64) time.Time {
+	seconds := int64(ntpTime>>32) - ntpEpochOffset
+	nanoseconds := int64(((ntpTime & 0xFFFFFFFF) * uint64(time.Second)) >> 32)
+	return time.Unix(seconds, nanoseconds)
+}
+
+// SetReadDeadline sets the deadline for all reads of the track. Like with net.Conn it also applies to
+// reads blocked at that time, they return ErrDeadlineExceeded. A zero value disables the deadline
+func (t *Track) SetReadDeadline(deadline time.Time) error {
+	t.readDeadline.set(deadline)
+	return nil
+}
+
+// checkPayloadType updates the codec of a remote track if the payload type of an incoming packet changed
+func (t *Track) checkPayloadType(b []byte) {
+	if len(b) < 2 {
+		return
+	}
+	payloadType := b[1] & 0x7f
+
+	t.mu.RLock()
+	unchanged := payloadType == t.payloadType
 	t.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	codec, err := t.receiver.codecForPayloadType(payloadType)
+	if err != nil {
+		return
+	} else if codec.Name == TelephoneEvent {
+		t.telephoneEventReceived(b, codec.ClockRate)
+		return
+	}
+
+	t.mu.Lock()
+	t.payloadType = payloadType
+	t.codec = codec
+	hdlr := t.onCodecChangeHandler
+	t.mu.Unlock()
+
+	if hdlr != nil {
+		hdlr(codec)
+	}
+}
 
-	return r.readRTP(b)
+// telephoneEventReceived calls the OnDTMF handler for the first packet that ends an event
+func (t *Track) telephoneEventReceived(b []byte, clockRate uint32) {
+	p := &rtp.Packet{}
+	if err := p.Unmarshal(b); err != nil {
+		return
+	}
+
+	tone, end, duration, ok := dtmfFromTelephoneEvent(p.Payload)
+	if !ok || !end {
+		return
+	}
+
+	t.mu.Lock()
+	if t.hasDTMF && t.lastDTMFTimestamp == p.Timestamp {
+		t.mu.Unlock()
+		return
+	}
+	t.lastDTMFTimestamp = p.Timestamp
+	t.hasDTMF = true
+	hdlr := t.onDTMFHandler
+	t.mu.Unlock()
+
+	if hdlr != nil && clockRate != 0 {
+		hdlr(tone, time.Duration(int64(duration)*int64(time.Second)/int64(clockRate)))
+	}
 }
 
-// ReadRTP is a convenience method that wraps Read and unmarshals for you
+// ReadRTP is a convenience method that wraps Read and unmarshals for you.
+// It allocates the packet returned and a copy of the bytes read, see ReadRTPInto
 func (t *Track) ReadRTP() (*rtp.Packet, error) {
-	b := make([]byte, receiveMTU)
-	i, err := t.Read(b)
+	return t.ReadRTPContext(context.Background())
+}
+
+// ReadRTPContext is like ReadRTP, but returns ctx.Err() if ctx is done before a packet is read
+func (t *Track) ReadRTPContext(ctx context.Context) (*rtp.Packet, error) {
+	b, err := readIntoPooledBuffer(func(b []byte) (int, error) {
+		return t.ReadContext(ctx, b)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	r := &rtp.Packet{}
-	if err := r.Unmarshal(b[:i]); err != nil {
+	if err := r.Unmarshal(b); err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
+// ReadSample reads packets from a remote track until a frame is complete and returns it, after the
+// transform of the RTPReceiver. Samples are returned once the first packet of the next frame arrived.
+// Only VP8, Opus and G722 can be depacketized. It must not be called concurrently with Read
+func (t *Track) ReadSample() (*media.Sample, error) {
+	return t.ReadSampleContext(context.Background())
+}
+
+// ReadSampleContext is like ReadSample, but returns ctx.Err() if ctx is done before a sample is read
+func (t *Track) ReadSampleContext(ctx context.Context) (*media.Sample, error) {
+	t.sampleMu.Lock()
+	defer t.sampleMu.Unlock()
+
+	for {
+		if t.sampleBuilder != nil {
+			for {
+				sample, timestamp := t.sampleBuilder.PopWithTimestamp()
+				if sample == nil {
+					break
+				}
+				if t.transformSample(sample, timestamp) {
+					return sample, nil
+				}
+			}
+		}
+
+		p, err := t.ReadRTPContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		codec := t.Codec()
+		if codec == nil || p.PayloadType != codec.PayloadType {
+			// Packets of other payload types, like telephone-events
+			continue
+		}
+		if codec != t.sampleCodec {
+			depacketizer, err := depacketizerForCodec(codec)
+			if err != nil {
+				return nil, err
+			}
+			t.sampleBuilder = samplebuilder.New(sampleBuilderMaxLate, depacketizer)
+			t.sampleCodec = codec
+		}
+		t.sampleBuilder.Push(p)
+	}
+}
+
+// transformSample applies the transform of the RTPReceiver, it returns false if the sample is dropped
+func (t *Track) transformSample(sample *media.Sample, timestamp uint32) bool {
+	t.mu.RLock()
+	receiver := t.receiver
+	ssrc, payloadType := t.ssrc, t.payloadType
+	t.mu.RUnlock()
+
+	var transform EncodedFrameTransform
+	if receiver != nil {
+		transform = receiver.getTransform()
+	}
+	if transform == nil {
+		return true
+	}
+
+	frame := &EncodedFrame{
+		Data:        sample.Data,
+		Keyframe:    isKeyframe(t.sampleCodec, sample.Data),
+		Timestamp:   timestamp,
+		SSRC:        ssrc,
+		PayloadType: payloadType,
+	}
+	if err := transform(frame); err != nil {
+		return false
+	}
+	sample.Data = frame.Data
+	return true
+}
+
+// ReadRTPInto reads a single packet into b and unmarshals it into p. Unlike ReadRTP it doesn't
+// allocate a packet and a copy of its bytes per call, the Payload of p references b, so b must not
+// be reused while p is in use
+func (t *Track) ReadRTPInto(p *rtp.Packet, b []byte) error {
+	i, err := t.Read(b)
+	if err != nil {
+		return err
+	}
+
+	return p.Unmarshal(b[:i])
+}
+
 // Write writes data to the track. If this is a remote track this will error
 func (t *Track) Write(b []byte) (n int, err error) {
 	packet := &rtp.Packet{}
@@ -121,36 +604,53 @@ func (t *Track) Write(b []byte) (n int, err error) {
 	return len(b), nil
 }
 
-// WriteSample packetizes and writes to the track
+// WriteSample packetizes and writes to the track. RTPSenders with a transform
+// packetize the transformed sample themselves
 func (t *Track) WriteSample(s media.Sample) error {
 	packets := t.packetizer.Packetize(s.Data, s.Samples)
-	for _, p := range packets {
-		err := t.WriteRTP(p)
-		if err != nil {
-			return err
+	if len(packets) == 0 {
+		return nil
+	}
+
+	senders, err := t.writableSenders()
+	if err != nil {
+		return err
+	}
+
+	for _, sender := range senders {
+		if transform := sender.getTransform(); transform != nil {
+			if err := sender.sendFrame(transform, s.Data, packets, s.AudioLevel); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, p := range packets {
+			if _, err := sender.sendRTP(&p.Header, p.Payload, s.AudioLevel); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
-// WriteRTP writes RTP packets to the track
+// WriteRTP writes RTP packets to the track. It returns ErrWriteRTPWithTransform if an RTPSender
+// of the track has a transform, the transform only applies to samples written with WriteSample
 func (t *Track) WriteRTP(p *rtp.Packet) error {
-	t.mu.RLock()
-	if t.receiver != nil {
-		t.mu.RUnlock()
-		return fmt.Errorf("this is a remote track and must not be written to")
+	senders, err := t.writableSenders()
+	if err != nil {
+		return err
 	}
-	senders := t.activeSenders
-	totalSenderCount := t.totalSenderCount
-	t.mu.RUnlock()
 
-	if totalSenderCount == 0 {
-		return io.ErrClosedPipe
+	for _, s := range senders {
+		if s.getTransform() != nil {
+			return ErrWriteRTPWithTransform
+		}
 	}
 
 	for _, s := range senders {
-		_, err := s.sendRTP(&p.Header, p.Payload)
+		_, err := s.sendRTP(&p.Header, p.Payload, nil)
 		if err != nil {
 			return err
 		}
@@ -159,10 +659,29 @@ func (t *Track) WriteRTP(p *rtp.Packet) error {
 	return nil
 }
 
-// NewTrack initializes a new *Track
-func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec) (*Track, error) {
+// writableSenders returns the senders packets written to a local track are sent with
+func (t *Track) writableSenders() ([]*RTPSender, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.receiver != nil {
+		return nil, fmt.Errorf("this is a remote track and must not be written to")
+	}
+	if t.totalSenderCount == 0 {
+		return nil, io.ErrClosedPipe
+	}
+	return t.activeSenders, nil
+}
+
+// NewTrack initializes a new *Track. If ssrc is zero a random SSRC is assigned.
+// Tracks with the same stream ID are announced as one MediaStream, so the remote can
+// synchronize them. Without streamIDs the label is used as the stream ID
+func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec, streamIDs ...string) (*Track, error) {
 	if ssrc == 0 {
-		return nil, fmt.Errorf("SSRC supplied to NewTrack() must be non-zero")
+		ssrc = randomSSRC(nil)
+	}
+	if len(streamIDs) == 0 {
+		streamIDs = []string{label}
 	}
 
 	packetizer := rtp.NewPacketizer(
@@ -179,23 +698,53 @@ func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec)
 		payloadType: payloadType,
 		kind:        codec.Type,
 		label:       label,
+		streamIDs:   append([]string{}, streamIDs...),
 		ssrc:        ssrc,
 		codec:       codec,
 		packetizer:  packetizer,
 	}, nil
 }
 
-// determinePayloadType blocks and reads a single packet to determine the PayloadType for this Track
-// this is useful if we are dealing with a remote track and we can't announce it to the user until we know the payloadType
+// randomSSRC returns a random non-zero SSRC that isn't in inUse
+func randomSSRC(inUse map[uint32]struct{}) uint32 {
+	for {
+		ssrc := mathRand.Uint32()
+		if _, ok := inUse[ssrc]; ssrc != 0 && !ok {
+			return ssrc
+		}
+	}
+}
+
+// determinePayloadType blocks and reads a single packet to determine the PayloadType and Codec for this Track
+// this is useful if we are dealing with a remote track and we can't announce it to the user until we know the payloadType.
+// The packet is kept so it is still returned by the next Read. Telephone-events sent before the media are dropped.
 func (t *Track) determinePayloadType() error {
-	r, err := t.ReadRTP()
-	if err != nil {
-		return err
+	b := make([]byte, receiveMTU)
+	var header *rtp.Header
+	var codec *RTPCodec
+	var n int
+	for codec == nil || codec.Name == TelephoneEvent {
+		var err error
+		if n, err = t.receiver.readRTP(b, nil, context.Background()); err != nil {
+			return err
+		}
+
+		header = &rtp.Header{}
+		if err = header.Unmarshal(b[:n]); err != nil {
+			return err
+		}
+
+		if codec, err = t.receiver.codecForPayloadType(header.PayloadType); err != nil {
+			return fmt.Errorf("no codec negotiated for payloadType %d", header.PayloadType)
+		}
 	}
 
 	t.mu.Lock()
-	t.payloadType = r.PayloadType
 	defer t.mu.Unlock()
+	t.peeked = b[:n]
+	t.payloadType = header.PayloadType
+	t.kind = codec.Type
+	t.codec = codec
 
 	return nil
 }
@@ -3,8 +3,15 @@
 package webrtc
 
 import (
+	"io"
+	"math"
 	"math/rand"
 	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewVideoTrack(t *testing.T) {
@@ -117,3 +124,346 @@ func TestNewTracksWrite(t *testing.T) {
 	}
 
 }
+
+func TestTrackPeekedPacketAndCodecChange(t *testing.T) {
+	vp8 := NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)
+	vp9 := NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000)
+	receiver := &RTPReceiver{codecs: []*RTPCodec{vp8, vp9}}
+
+	peeked, err := (&rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeVP8}, Payload: []byte{0x01}}).Marshal()
+	assert.NoError(t, err)
+
+	track := &Track{receiver: receiver, payloadType: DefaultPayloadTypeVP8, codec: vp8, peeked: peeked}
+
+	_, err = track.Read(make([]byte, 1))
+	assert.Equal(t, io.ErrShortBuffer, err)
+
+	p, err := track.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, p.Payload)
+
+	var changedTo *RTPCodec
+	track.OnCodecChange(func(c *RTPCodec) {
+		changedTo = c
+	})
+
+	vp9Packet, err := (&rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeVP9}}).Marshal()
+	assert.NoError(t, err)
+
+	track.checkPayloadType(peeked)
+	assert.Nil(t, changedTo)
+
+	track.checkPayloadType(vp9Packet)
+	assert.Equal(t, vp9, changedTo)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP9), track.PayloadType())
+	assert.Equal(t, vp9, track.Codec())
+}
+
+func TestTrackSenderReportSync(t *testing.T) {
+	wallClock := time.Unix(1500000000, int64(250*time.Millisecond))
+	ntpTime := uint64(wallClock.Unix()+ntpEpochOffset)<<32 | uint64(1)<<30
+
+	newRemoteTrack := func(codec *RTPCodec, srTimestamp, firstTimestamp uint32) *Track {
+		receiver := &RTPReceiver{codecs: []*RTPCodec{codec}}
+		peeked, err := (&rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: codec.PayloadType, Timestamp: firstTimestamp}}).Marshal()
+		assert.NoError(t, err)
+
+		track := &Track{receiver: receiver, payloadType: codec.PayloadType, codec: codec, peeked: peeked, streamIDs: []string{"stream"}}
+		receiver.track = track
+
+		_, ok := track.RTPTimestampToTime(srTimestamp)
+		assert.False(t, ok)
+
+		compound, err := rtcp.Marshal([]rtcp.Packet{
+			&rtcp.SenderReport{SSRC: track.SSRC(), NTPTime: ntpTime, RTPTime: srTimestamp},
+			&rtcp.SourceDescription{},
+		})
+		assert.NoError(t, err)
+		receiver.handleRTCP(compound)
+
+		_, err = track.ReadRTP()
+		assert.NoError(t, err)
+		return track
+	}
+
+	// Audio starts one second after the sender report, video half a second after it
+	audio := newRemoteTrack(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000), 10000, 10000+48000)
+	video := newRemoteTrack(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000), math.MaxUint32-1000, 45000-1001)
+
+	at, ok := audio.RTPTimestampToTime(10000)
+	assert.True(t, ok)
+	assert.Equal(t, wallClock, at)
+
+	at, ok = audio.RTPTimestampToTime(10000 - 4800)
+	assert.True(t, ok)
+	assert.Equal(t, wallClock.Add(-100*time.Millisecond), at)
+
+	at, ok = video.RTPTimestampToTime(90000 - 1001)
+	assert.True(t, ok)
+	assert.Equal(t, wallClock.Add(time.Second), at)
+
+	offset, ok := audio.SyncOffset(video)
+	assert.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, offset)
+
+	offset, ok = video.SyncOffset(audio)
+	assert.True(t, ok)
+	assert.Equal(t, -500*time.Millisecond, offset)
+
+	video.streamIDs = []string{"other"}
+	_, ok = audio.SyncOffset(video)
+	assert.False(t, ok)
+}
+
+// newBenchmarkForwardPair connects two PeerConnections like an SFU and its client. source is sent by
+// pcOffer and received as remote by pcAnswer, packets written to forward are sent back to pcOffer and discarded.
+// Packets pass through the ICE, SRTP and RTPReceiver read path like in production
+func newBenchmarkForwardPair(b *testing.B) (source, remote, forward *Track, closeFunc func()) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if source, err = pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion"); err != nil {
+		b.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(source); err != nil {
+		b.Fatal(err)
+	}
+	if forward, err = pcAnswer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "forward", "pion"); err != nil {
+		b.Fatal(err)
+	}
+	if _, err = pcAnswer.AddTrack(forward); err != nil {
+		b.Fatal(err)
+	}
+
+	remoteTracks := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		remoteTracks <- track
+	})
+	pcOffer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		buf := make([]byte, receiveMTU)
+		for {
+			if _, readErr := track.Read(buf); readErr != nil {
+				return
+			}
+		}
+	})
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		b.Fatal(err)
+	}
+
+	// The remote track is only announced once a packet arrived
+	packet := benchmarkPacket(b)
+	for remote == nil {
+		if err = source.WriteRTP(packet); err != nil {
+			b.Fatal(err)
+		}
+		select {
+		case remote = <-remoteTracks:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	// Drain what was sent while waiting
+	assert.NoError(b, remote.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	buf := make([]byte, receiveMTU)
+	for {
+		if _, err = remote.Read(buf); err != nil {
+			break
+		}
+	}
+	assert.NoError(b, remote.SetReadDeadline(time.Time{}))
+
+	return source, remote, forward, func() {
+		assert.NoError(b, pcOffer.Close())
+		assert.NoError(b, pcAnswer.Close())
+	}
+}
+
+func benchmarkPacket(b *testing.B) *rtp.Packet {
+	return &rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeVP8, SSRC: 1234},
+		Payload: make([]byte, 1200),
+	}
+}
+
+func BenchmarkTrackReadRTP(b *testing.B) {
+	packet := benchmarkPacket(b)
+	source, remote, _, closeFunc := newBenchmarkForwardPair(b)
+	defer closeFunc()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		packet.SequenceNumber++
+		if err := source.WriteRTP(packet); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := remote.ReadRTP(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTrackReadRTPInto(b *testing.B) {
+	packet := benchmarkPacket(b)
+	source, remote, _, closeFunc := newBenchmarkForwardPair(b)
+	defer closeFunc()
+
+	p := &rtp.Packet{}
+	buf := make([]byte, receiveMTU)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		packet.SequenceNumber++
+		if err := source.WriteRTP(packet); err != nil {
+			b.Fatal(err)
+		}
+		if err := remote.ReadRTPInto(p, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTrackForward reads from a remote Track and writes to a local Track like an SFU does.
+// The allocations reported include sending the source packets and discarding the forwarded ones.
+// None are made by this package, see TestTrackForwardAllocations, they are made by pion/srtp when
+// encrypting and decrypting, by the packetio buffers of the mux and the SRTP read streams, and by
+// pion/ice and net per datagram
+func BenchmarkTrackForward(b *testing.B) {
+	packet := benchmarkPacket(b)
+	source, remote, forward, closeFunc := newBenchmarkForwardPair(b)
+	defer closeFunc()
+
+	p := &rtp.Packet{}
+	buf := make([]byte, receiveMTU)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		packet.SequenceNumber++
+		if err := source.WriteRTP(packet); err != nil {
+			b.Fatal(err)
+		}
+		if err := remote.ReadRTPInto(p, buf); err != nil {
+			b.Fatal(err)
+		}
+		if err := forward.WriteRTP(p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// loopStream is a readStream returning the same packet on every Read
+type loopStream []byte
+
+func (s loopStream) Read(b []byte) (int, error) {
+	if len(b) < len(s) {
+		return 0, io.ErrShortBuffer
+	}
+	return copy(b, s), nil
+}
+
+func (s loopStream) Close() error {
+	return nil
+}
+
+// discardWriteStream is an rtpWriteStream dropping the packets written
+type discardWriteStream struct{}
+
+func (discardWriteStream) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	return len(payload), nil
+}
+
+// newFakeForwardPair returns a remote Track reading the same packet forever and a local Track discarding
+// the packets written. Unlike newBenchmarkForwardPair only the path of this package is used, the streams
+// take the place of SRTP, the mux and ICE
+func newFakeForwardPair(t testing.TB) (remote, forward *Track, closeFunc func()) {
+	api := NewAPI()
+	codec := NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)
+
+	raw, err := (&rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeVP8, SSRC: 1234},
+		Payload: make([]byte, 1200),
+	}).Marshal()
+	assert.NoError(t, err)
+
+	receiver, err := api.NewRTPReceiver(RTPCodecTypeVideo, &DTLSTransport{})
+	assert.NoError(t, err)
+	remote = &Track{ssrc: 1234, payloadType: DefaultPayloadTypeVP8, codec: codec, receiver: receiver}
+	receiver.track = remote
+	receiver.codecs = []*RTPCodec{codec}
+	receiver.rtpReadStream = loopStream(raw)
+	close(receiver.received)
+
+	forward, err = NewTrack(DefaultPayloadTypeVP8, 5678, "forward", "pion", codec)
+	assert.NoError(t, err)
+	sender, err := api.NewRTPSender(forward, &DTLSTransport{})
+	assert.NoError(t, err)
+	sender.rtpWriteStream = discardWriteStream{}
+	close(sender.sendCalled)
+	forward.activeSenders = []*RTPSender{sender}
+
+	return remote, forward, func() {
+		receiver.rtpReader.close()
+	}
+}
+
+func TestTrackForwardAllocations(t *testing.T) {
+	remote, forward, closeFunc := newFakeForwardPair(t)
+	defer closeFunc()
+
+	p := &rtp.Packet{}
+	buf := make([]byte, receiveMTU)
+
+	// Routines left running by other tests are counted too, an allocation per packet shows in every attempt
+	allocs := math.Inf(1)
+	for i := 0; i < 5 && allocs != 0; i++ {
+		allocs = math.Min(allocs, testing.AllocsPerRun(1000, func() {
+			assert.NoError(t, remote.ReadRTPInto(p, buf))
+			assert.NoError(t, forward.WriteRTP(p))
+		}))
+	}
+	assert.Zero(t, allocs)
+}
+
+// BenchmarkTrackForwardPath is BenchmarkTrackForward without the SRTP, mux and ICE below this package
+func BenchmarkTrackForwardPath(b *testing.B) {
+	remote, forward, closeFunc := newFakeForwardPair(b)
+	defer closeFunc()
+
+	p := &rtp.Packet{}
+	buf := make([]byte, receiveMTU)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := remote.ReadRTPInto(p, buf); err != nil {
+			b.Fatal(err)
+		}
+		if err := forward.WriteRTP(p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRTPSenderRewriteHeader(b *testing.B) {
+	sender := &RTPSender{ssrc: 5678, payloadType: DefaultPayloadTypeVP8}
+	header := &rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeVP8, SSRC: 1234}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		header.SequenceNumber++
+
+		sender.sendMu.Lock()
+		sender.rewriteHeader(header, nil)
+		sender.sendMu.Unlock()
+	}
+}
@@ -0,0 +1,193 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// UnhandledStream is an incoming RTP or RTCP stream with an SSRC that isn't announced in the
+// remote description, for example when the remote omits a=ssrc or sends probing or RTX.
+// An RTP stream can be adopted as a Track with NewTrack.
+type UnhandledStream struct {
+	mu sync.Mutex
+
+	ssrc        uint32
+	rtcp        bool
+	firstPacket []byte
+	peeked      []byte
+
+	rtpReadStream  readStream
+	rtcpReadStream readStream
+	receiver       *RTPReceiver
+	closed         bool
+
+	pc *PeerConnection
+}
+
+// SSRC returns the SSRC of the stream
+func (s *UnhandledStream) SSRC() uint32 {
+	return s.ssrc
+}
+
+// IsRTCP returns true for a stream of RTCP packets and false for RTP
+func (s *UnhandledStream) IsRTCP() bool {
+	return s.rtcp
+}
+
+// FirstPacket returns the packet that revealed the stream, it is also returned by the first Read
+func (s *UnhandledStream) FirstPacket() []byte {
+	return append([]byte{}, s.firstPacket...)
+}
+
+// Read reads the next packet of the stream
+func (s *UnhandledStream) Read(b []byte) (int, error) {
+	s.mu.Lock()
+	switch {
+	case s.receiver != nil:
+		s.mu.Unlock()
+		return 0, fmt.Errorf("stream has been adopted as a Track and must be read from it")
+	case s.peeked != nil:
+		defer s.mu.Unlock()
+		if len(b) < len(s.peeked) {
+			return 0, io.ErrShortBuffer
+		}
+		n := copy(b, s.peeked)
+		s.peeked = nil
+		return n, nil
+	}
+	s.mu.Unlock()
+
+	if s.rtcp {
+		return s.rtcpReadStream.Read(b)
+	}
+	return s.rtpReadStream.Read(b)
+}
+
+// NewTrack adopts an RTP stream as a remote Track. If codec is nil it is looked up in the MediaEngine
+// with the payload type of the first packet. Packets not read from the stream yet are returned by the Track,
+// it is stopped with the PeerConnection
+func (s *UnhandledStream) NewTrack(codec *RTPCodec) (*Track, *RTPReceiver, error) {
+	if s.rtcp {
+		return nil, nil, fmt.Errorf("RTCP stream can not be adopted as a Track")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.closed:
+		return nil, nil, fmt.Errorf("stream has been closed")
+	case s.receiver != nil:
+		return nil, nil, fmt.Errorf("stream has already been adopted as a Track")
+	}
+
+	if codec == nil {
+		header := &rtp.Header{}
+		if err := header.Unmarshal(s.firstPacket); err != nil {
+			return nil, nil, err
+		}
+
+		var err error
+		if codec, err = s.pc.api.mediaEngine.getCodec(header.PayloadType); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	receiver, err := s.pc.api.NewRTPReceiver(codec.Type, s.pc.dtlsTransport)
+	if err != nil {
+		return nil, nil, err
+	}
+	receiver.onStoppedHdlr = func() {
+		s.pc.mu.Lock()
+		defer s.pc.mu.Unlock()
+		delete(s.pc.adoptedReceivers, receiver)
+	}
+
+	// The stream is still registered in the SRTP session, Receive opens it again
+	if err = receiver.Receive(RTPReceiveParameters{
+		Encodings: RTPDecodingParameters{RTPCodingParameters{SSRC: s.ssrc}},
+		Codecs:    []*RTPCodec{codec},
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	track := receiver.Track()
+	track.mu.Lock()
+	track.peeked = s.peeked
+	track.mu.Unlock()
+
+	s.peeked = nil
+	s.receiver = receiver
+
+	// From now on the receiver is stopped with the PeerConnection
+	s.pc.mu.Lock()
+	closed := s.pc.isClosed
+	delete(s.pc.unhandledStreams, s)
+	if !closed {
+		s.pc.adoptedReceivers[receiver] = struct{}{}
+	}
+	s.pc.mu.Unlock()
+
+	if closed {
+		if err = receiver.Stop(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+	return track, receiver, nil
+}
+
+// Close stops reading the stream, packets received with its SSRC are dropped from now on.
+// Closing an adopted stream stops its Track
+func (s *UnhandledStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	s.pc.mu.Lock()
+	delete(s.pc.unhandledStreams, s)
+	s.pc.addClosedUnhandledSSRC(unhandledSSRC{s.ssrc, s.rtcp})
+	s.pc.mu.Unlock()
+
+	if s.receiver != nil {
+		return s.receiver.Stop()
+	} else if s.rtcp {
+		return s.rtcpReadStream.Close()
+	}
+	return s.rtpReadStream.Close()
+}
+
+// unhandledSSRC identifies the RTP or RTCP stream of an SSRC
+type unhandledSSRC struct {
+	ssrc uint32
+	rtcp bool
+}
+
+// maxClosedUnhandledSSRCs limits the closed unhandled streams a PeerConnection remembers. Once
+// forgotten, packets of a closed stream are reported by OnUnhandledStream again
+const maxClosedUnhandledSSRCs = 1000
+
+// addClosedUnhandledSSRC remembers the SSRC of a closed unhandled stream so its packets are
+// dropped, forgetting the oldest one above maxClosedUnhandledSSRCs. Callers must hold pc.mu
+func (pc *PeerConnection) addClosedUnhandledSSRC(ssrc unhandledSSRC) {
+	if _, ok := pc.closedUnhandledSSRCs[ssrc]; ok {
+		return
+	}
+
+	pc.closedUnhandledSSRCs[ssrc] = struct{}{}
+	pc.closedUnhandledOrder = append(pc.closedUnhandledOrder, ssrc)
+	if len(pc.closedUnhandledOrder) > maxClosedUnhandledSSRCs {
+		delete(pc.closedUnhandledSSRCs, pc.closedUnhandledOrder[0])
+		pc.closedUnhandledOrder = pc.closedUnhandledOrder[1:]
+	}
+}